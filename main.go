@@ -3,25 +3,48 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"iter"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/grzadr/subgonverter/subtitle"
 )
 
 const (
 	writeBufferSize = 256 * 1024
+	stdinName       = "<stdin>"
 )
 
 type MainConfig struct {
-	InputPath    string
-	InputFormat  subtitle.FileFormat
-	OutputPath   string
-	OutputFormat subtitle.FileFormat
+	InputPath       string
+	InputFormat     subtitle.FileFormat
+	OutputPath      string
+	OutputFormat    subtitle.FileFormat
+	FrameRate       subtitle.FrameRate
+	FrameRateHeader bool
+	Lenient         bool
+	// Shift and Sync may be given in frames, counted at the rate of the
+	// cues once read and converted
+	Shift       subtitle.Offset
+	ShiftPolicy subtitle.ShiftPolicy
+	Sync        []subtitle.Anchor
+	// ConvertFrom and ConvertTo are set to rescale timings between rates
+	ConvertFrom subtitle.FrameRate
+	ConvertTo   subtitle.FrameRate
+	// DisplayTime and DisplayPolicy end cues read without an end time
+	DisplayTime   time.Duration
+	DisplayPolicy subtitle.DisplayPolicy
+	// Track selects a track of inputs with several, e.g. a SAMI class
+	Track string
+	// Language of the output, overriding the one read from the input
+	Language string
 }
 
 func ParseArguments(args []string) (parsed MainConfig, err error) {
@@ -30,12 +53,172 @@ func ParseArguments(args []string) (parsed MainConfig, err error) {
 	outputPath := fs.String("o", "-", "output file path (default: stdout)")
 	fs.String("output", "-", "output file path (default: stdout)")
 
+	formats := strings.Join(subtitle.SupportedFormats(), ", ")
+	fromUsage := "input format, one of: " + formats +
+		" (default: detected from extension and content)"
+	toUsage := "output format, one of: " + formats +
+		" (default: detected from output extension, or srt)"
+
+	inputFormat := fs.String("f", "", fromUsage)
+	fs.String("from", "", fromUsage)
+	outputFormat := fs.String("t", "", toUsage)
+	fs.String("to", "", toUsage)
+
+	frameRate := fs.String(
+		"fps",
+		"",
+		"MicroDVD frame rate of the input and output, e.g. 25, 29.97 or "+
+			"24000/1001, overriding a {1}{1}fps header of the input "+
+			"(default: the header rate, or 23.976)",
+	)
+	frameRateHeader := fs.Bool(
+		"fps-header",
+		false,
+		"write the {1}{1}fps header line to MicroDVD output "+
+			"(default: only when the MicroDVD input has one)",
+	)
+
+	lenient := fs.Bool(
+		"lenient",
+		false,
+		"skip malformed cues and report them at the end instead of stopping",
+	)
+
+	shift := fs.String(
+		"shift",
+		"",
+		"move every cue by an offset, e.g. +2.5s, -00:00:01,200 or -48f",
+	)
+	shiftPolicy := fs.String(
+		"shift-policy",
+		"clamp",
+		"what to do with cues shifted before zero: clamp, drop or error",
+	)
+
+	displayTime := fs.Duration(
+		"display-time",
+		0,
+		"display time of cues without an end time, e.g. TMPlayer cues "+
+			"(default: 3s)",
+	)
+	displayPolicy := fs.String(
+		"display-policy",
+		"default",
+		"how cues without an end time end: default, fixed or until-next",
+	)
+
+	track := fs.String(
+		"track",
+		"",
+		"track to read from inputs with several, e.g. the SAMI class KRCC "+
+			"or its language ko-KR (default: all, or the first for "+
+			"output formats with a single track)",
+	)
+
+	language := fs.String(
+		"lang",
+		"",
+		"language of the output, e.g. en or ko-KR, required by imsc1 for "+
+			"inputs without one (default: from the input)",
+	)
+
+	convertFps := fs.String(
+		"convert-fps",
+		"",
+		"rescale timings between frame rates FROM:TO, e.g. 23.976:25",
+	)
+
+	var syncs []string
+
+	fs.Func(
+		"sync",
+		"resync anchor CUE=TIME or TIME=TIME, e.g. 812=01:43:10,000 "+
+			"(repeatable)",
+		func(value string) error {
+			syncs = append(syncs, value)
+			return nil
+		},
+	)
+	syncFile := fs.String(
+		"sync-file",
+		"",
+		"read --sync anchors from a file, one per line",
+	)
+
 	if err := fs.Parse(args); err != nil {
 		return parsed, fmt.Errorf("failed to parse flags: %w", err)
 	}
 
-	// Set defaults
-	parsed.InputFormat = subtitle.TxtFormat
+	if *frameRate != "" {
+		parsed.FrameRate, err = subtitle.ParseFrameRate(*frameRate)
+		if err != nil {
+			return parsed, fmt.Errorf("failed to parse --fps: %w", err)
+		}
+	}
+
+	if *convertFps != "" {
+		if *frameRate != "" {
+			return parsed, errors.New(
+				"--fps cannot be combined with --convert-fps, " +
+					"which sets both the input and output rates",
+			)
+		}
+
+		parsed.ConvertFrom, parsed.ConvertTo, err = parseConversion(*convertFps)
+		if err != nil {
+			return parsed, fmt.Errorf("failed to parse --convert-fps: %w", err)
+		}
+	}
+
+	parsed.FrameRateHeader = *frameRateHeader
+	parsed.Lenient = *lenient
+	parsed.Track = *track
+	parsed.Language = *language
+
+	if *shift != "" {
+		parsed.Shift, err = subtitle.ParseOffset(*shift)
+		if err != nil {
+			return parsed, fmt.Errorf("failed to parse --shift: %w", err)
+		}
+	}
+
+	parsed.ShiftPolicy, err = subtitle.ParseShiftPolicy(*shiftPolicy)
+	if err != nil {
+		return parsed, fmt.Errorf("failed to parse --shift-policy: %w", err)
+	}
+
+	if *displayTime < 0 {
+		return parsed, fmt.Errorf(
+			"failed to parse --display-time: negative duration %v",
+			*displayTime,
+		)
+	}
+
+	parsed.DisplayTime = *displayTime
+
+	parsed.DisplayPolicy, err = subtitle.ParseDisplayPolicy(*displayPolicy)
+	if err != nil {
+		return parsed, fmt.Errorf("failed to parse --display-policy: %w", err)
+	}
+
+	if *syncFile != "" {
+		parsed.Sync, err = readAnchors(*syncFile)
+		if err != nil {
+			return parsed, fmt.Errorf("failed to parse --sync-file: %w", err)
+		}
+	}
+
+	for _, value := range syncs {
+		anchor, err := subtitle.ParseAnchor(value)
+		if err != nil {
+			return parsed, fmt.Errorf("failed to parse --sync: %w", err)
+		}
+
+		parsed.Sync = append(parsed.Sync, anchor)
+	}
+
+	// Set defaults, the input format is detected when processing
+	parsed.InputFormat = subtitle.UnknownFormat
 	parsed.OutputPath = *outputPath
 	parsed.OutputFormat = subtitle.SrtFormat
 
@@ -44,6 +227,35 @@ func ParseArguments(args []string) (parsed MainConfig, err error) {
 		parsed.OutputPath = output
 	}
 
+	// Infer the output format from the output extension
+	detected := subtitle.FormatFromExtension(parsed.OutputPath)
+	if detected != subtitle.UnknownFormat {
+		parsed.OutputFormat = detected
+	}
+
+	// Handle the format flags the same way as the output flag
+	if from := fs.Lookup("from").Value.String(); from != "" {
+		*inputFormat = from
+	}
+
+	if to := fs.Lookup("to").Value.String(); to != "" {
+		*outputFormat = to
+	}
+
+	if *inputFormat != "" {
+		parsed.InputFormat, err = subtitle.ParseFileFormat(*inputFormat)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid input format: %w", err)
+		}
+	}
+
+	if *outputFormat != "" {
+		parsed.OutputFormat, err = subtitle.ParseFileFormat(*outputFormat)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid output format: %w", err)
+		}
+	}
+
 	// Get optional positional argument for input file
 	if fs.NArg() > 0 {
 		parsed.InputPath = fs.Arg(0)
@@ -52,6 +264,65 @@ func ParseArguments(args []string) (parsed MainConfig, err error) {
 	return parsed, nil
 }
 
+// parseConversion parses a FROM:TO pair of frame rates.
+func parseConversion(
+	value string,
+) (from, to subtitle.FrameRate, err error) {
+	fromValue, toValue, found := strings.Cut(value, ":")
+	if !found {
+		return from, to, fmt.Errorf("expected FROM:TO, got %q", value)
+	}
+
+	if from, err = subtitle.ParseFrameRate(fromValue); err != nil {
+		return from, to, err
+	}
+
+	to, err = subtitle.ParseFrameRate(toValue)
+
+	return from, to, err
+}
+
+func readAnchors(path string) ([]subtitle.Anchor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return subtitle.ParseAnchors(file)
+}
+
+// afterFirst applies transform to subs once their first cue is read, as
+// readers fill the document before yielding it, e.g. with the frame rate of
+// a MicroDVD header.
+func afterFirst(
+	subs iter.Seq2[subtitle.Subtitle, error],
+	transform func(
+		iter.Seq2[subtitle.Subtitle, error],
+	) iter.Seq2[subtitle.Subtitle, error],
+) iter.Seq2[subtitle.Subtitle, error] {
+	return func(yield func(subtitle.Subtitle, error) bool) {
+		next, stop := iter.Pull2(subs)
+		defer stop()
+
+		sub, err, ok := next()
+
+		rest := func(yield func(subtitle.Subtitle, error) bool) {
+			for ; ok; sub, err, ok = next() {
+				if !yield(sub, err) {
+					return
+				}
+			}
+		}
+
+		for sub, err := range transform(rest) {
+			if !yield(sub, err) {
+				return
+			}
+		}
+	}
+}
+
 func InitReader(path string) (io.Reader, func() error, error) {
 	if path == "" || path == "-" {
 		return os.Stdin, func() error { return nil }, nil
@@ -92,36 +363,157 @@ func InitWriter(path string) (io.Writer, func() error, error) {
 func process(
 	ctx context.Context,
 	config MainConfig,
-) error {
+) (err error) {
 	reader, rcloser, err := InitReader(config.InputPath)
 	if err != nil {
 		return fmt.Errorf("failed to initialize input reader: %w", err)
 	}
 	defer rcloser()
 
+	name := config.InputPath
+	if name == "" || name == "-" {
+		name = stdinName
+	}
+
+	format := config.InputFormat
+	if format == subtitle.UnknownFormat {
+		format, reader, err = subtitle.DetectFormat(name, reader)
+		if err != nil {
+			return fmt.Errorf("%w, use --from to select it", err)
+		}
+	}
+
 	writer, wcloser, err := InitWriter(config.OutputPath)
 	if err != nil {
 		return fmt.Errorf("failed to initialize output writer: %w", err)
 	}
 	defer wcloser()
 
-	print := subtitle.NewSubtitlePrinter(writer, config.OutputFormat)
+	// The reader fills the document before the printer writes the header
+	doc := &subtitle.Document{}
+
+	// Frame-based formats are read at the source and written at the target
+	// rate of a conversion
+	inputRate, outputRate := config.FrameRate, config.FrameRate
+	if !config.ConvertTo.IsZero() {
+		inputRate, outputRate = config.ConvertFrom, config.ConvertTo
+	}
+
+	printer := subtitle.NewSubtitlePrinterWithConfig(
+		writer,
+		config.OutputFormat,
+		subtitle.PrinterConfig{
+			FrameRate:       outputRate,
+			FrameRateHeader: config.FrameRateHeader,
+			Document:        doc,
+			Language:        config.Language,
+		},
+	)
+	if printer == nil {
+		return fmt.Errorf(
+			"unsupported output format: %s",
+			config.OutputFormat,
+		)
+	}
+
+	// Close finishes the document, before the writer itself is closed. A
+	// failure other than skipped cues leaves the document unfinished.
+	defer func() {
+		var skipped *subtitle.SkippedCuesError
+		if err != nil && !errors.As(err, &skipped) {
+			printer.Abort()
+			return
+		}
+
+		if closeErr := printer.Close(); closeErr != nil {
+			err = errors.Join(
+				err,
+				fmt.Errorf("failed to finish output: %w", closeErr),
+			)
+		}
+	}()
+
+	// Inputs with several tracks are read whole only by formats keeping
+	// them, the others get the first track unless one is selected
+	outputFormat := subtitle.Formats()[config.OutputFormat]
+
+	subs := subtitle.NewSubtitlesIterWithConfig(
+		reader,
+		format,
+		subtitle.ReaderConfig{
+			Name:          name,
+			FrameRate:     inputRate,
+			Lenient:       config.Lenient,
+			Document:      doc,
+			DisplayTime:   config.DisplayTime,
+			DisplayPolicy: config.DisplayPolicy,
+			Track:         config.Track,
+			SingleTrack:   !outputFormat.Tracks,
+		},
+	)
+
+	if !config.ConvertTo.IsZero() {
+		subs = subtitle.ConvertFrameRate(
+			subs,
+			config.ConvertFrom,
+			config.ConvertTo,
+		)
+	}
+
+	if len(config.Sync) > 0 || config.Shift != (subtitle.Offset{}) {
+		// Frames are counted at the rate of the cues once converted, which
+		// may come from the header of the input
+		subs = afterFirst(subs, func(
+			subs iter.Seq2[subtitle.Subtitle, error],
+		) iter.Seq2[subtitle.Subtitle, error] {
+			rate := outputRate
+			if rate.IsZero() {
+				rate = doc.FrameRate
+			}
 
-	for sub, err := range subtitle.NewSubtitlesIter(reader, config.InputFormat) {
+			if len(config.Sync) > 0 {
+				subs = subtitle.Resync(subs, config.Sync, rate)
+			}
+
+			if config.Shift != (subtitle.Offset{}) {
+				subs = subtitle.Shift(
+					subs,
+					config.Shift,
+					rate,
+					config.ShiftPolicy,
+				)
+			}
+
+			return subs
+		})
+	}
+
+	// Skipped cues are reported once the rest has been converted
+	var skipped *subtitle.SkippedCuesError
+
+	for sub, err := range subs {
+
+		if errors.As(err, &skipped) {
+			continue
+		}
 
 		if err != nil {
-			return fmt.Errorf("failed to parse subtitle: %s", err)
+			return fmt.Errorf("failed to parse subtitle: %w", err)
 		}
 
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		if err := print(sub); err != nil {
+		if err := printer.Write(sub); err != nil {
 			return fmt.Errorf("failed to write subtitle: %w", err)
 		}
 	}
 
+	if skipped != nil {
+		return skipped
+	}
+
 	return nil
 }
 
@@ -135,6 +527,24 @@ func main() {
 	}
 
 	if err := process(ctx, config); err != nil {
+		// Skipped cues alone do not fail a lenient conversion
+		var skipped *subtitle.SkippedCuesError
+		if errors.As(err, &skipped) {
+			if err != error(skipped) {
+				log.Fatalf("processing failed: %v", err)
+			}
+
+			fmt.Fprintln(os.Stderr, skipped)
+			return
+		}
+
+		// Report parse errors as file:line:column, so editors can jump there
+		var parseErr *subtitle.ParseError
+		if errors.As(err, &parseErr) {
+			fmt.Fprintln(os.Stderr, parseErr)
+			os.Exit(1)
+		}
+
 		log.Fatalf("processing failed: %v", err)
 	}
 }
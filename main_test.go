@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grzadr/subgonverter/subtitle"
 )
@@ -21,7 +24,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{},
 			wantConfig: MainConfig{
 				InputPath:    "",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "-",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -31,7 +34,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"input.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "-",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -41,7 +44,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"-o", "output.srt"},
 			wantConfig: MainConfig{
 				InputPath:    "",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "output.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -51,7 +54,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"--output", "output.srt"},
 			wantConfig: MainConfig{
 				InputPath:    "",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "output.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -61,7 +64,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"-o", "output.srt", "input.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "output.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -71,7 +74,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"--output", "output.srt", "input.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "output.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -81,7 +84,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"input.txt", "-o", "output.srt"},
 			wantConfig: MainConfig{
 				InputPath:    "input.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "-",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -91,7 +94,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"-o", "-", "input.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "-",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -101,7 +104,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"--output", "-", "input.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "-",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -111,7 +114,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"-o", "file1.srt", "--output", "file2.srt"},
 			wantConfig: MainConfig{
 				InputPath:    "",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "file2.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -121,7 +124,7 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"input1.txt", "input2.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input1.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "-",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -131,17 +134,97 @@ func TestParseArguments(t *testing.T) {
 			args: []string{"-o", "output file.srt", "input file.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "input file.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "output file.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
 		},
+		{
+			name: "input and output format with short flags",
+			args: []string{"-f", "srt", "-t", "txt", "input.srt"},
+			wantConfig: MainConfig{
+				InputPath:    "input.srt",
+				InputFormat:  subtitle.SrtFormat,
+				OutputPath:   "-",
+				OutputFormat: subtitle.TxtFormat,
+			},
+		},
+		{
+			name: "input and output format with long flags",
+			args: []string{"--from", "ass", "--to", "vtt", "input.ass"},
+			wantConfig: MainConfig{
+				InputPath:    "input.ass",
+				InputFormat:  subtitle.AssFormat,
+				OutputPath:   "-",
+				OutputFormat: subtitle.VttFormat,
+			},
+		},
+		{
+			name: "format aliases are case insensitive",
+			args: []string{"--from", "SubRip", "--to", "WebVTT"},
+			wantConfig: MainConfig{
+				InputPath:    "",
+				InputFormat:  subtitle.SrtFormat,
+				OutputPath:   "-",
+				OutputFormat: subtitle.VttFormat,
+			},
+		},
+		{
+			name: "--to takes precedence over -t",
+			args: []string{"-t", "vtt", "--to", "ass"},
+			wantConfig: MainConfig{
+				InputPath:    "",
+				InputFormat:  subtitle.UnknownFormat,
+				OutputPath:   "-",
+				OutputFormat: subtitle.AssFormat,
+			},
+		},
+		{
+			name: "output format from extension",
+			args: []string{"-o", "output.vtt", "input.srt"},
+			wantConfig: MainConfig{
+				InputPath:    "input.srt",
+				InputFormat:  subtitle.UnknownFormat,
+				OutputPath:   "output.vtt",
+				OutputFormat: subtitle.VttFormat,
+			},
+		},
+		{
+			name: "output format from uppercase extension",
+			args: []string{"-o", "OUTPUT.TXT"},
+			wantConfig: MainConfig{
+				InputPath:    "",
+				InputFormat:  subtitle.UnknownFormat,
+				OutputPath:   "OUTPUT.TXT",
+				OutputFormat: subtitle.TxtFormat,
+			},
+		},
+		{
+			name: "unknown output extension defaults to srt",
+			args: []string{"-o", "output.out"},
+			wantConfig: MainConfig{
+				InputPath:    "",
+				InputFormat:  subtitle.UnknownFormat,
+				OutputPath:   "output.out",
+				OutputFormat: subtitle.SrtFormat,
+			},
+		},
+		{
+			name: "--to takes precedence over the output extension",
+			args: []string{"-o", "output.txt", "--to", "ass"},
+			wantConfig: MainConfig{
+				InputPath:    "",
+				InputFormat:  subtitle.UnknownFormat,
+				OutputPath:   "output.txt",
+				OutputFormat: subtitle.AssFormat,
+			},
+		},
 		{
 			name: "file path with special characters",
 			args: []string{"-o", "out-put_2024.srt", "in-put_2024.txt"},
 			wantConfig: MainConfig{
 				InputPath:    "in-put_2024.txt",
-				InputFormat:  subtitle.TxtFormat,
+				InputFormat:  subtitle.UnknownFormat,
 				OutputPath:   "out-put_2024.srt",
 				OutputFormat: subtitle.SrtFormat,
 			},
@@ -172,6 +255,146 @@ func TestParseArguments(t *testing.T) {
 	}
 }
 
+func TestParseArguments_FrameRate(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantRate   subtitle.FrameRate
+		wantHeader bool
+	}{
+		{
+			name: "default frame rate is left unset",
+			args: []string{"input.txt"},
+		},
+		{
+			name:     "PAL frame rate",
+			args:     []string{"--fps", "25", "input.txt"},
+			wantRate: subtitle.FrameRate{Num: 25, Den: 1},
+		},
+		{
+			name:     "NTSC frame rate",
+			args:     []string{"-fps", "29.97", "input.txt"},
+			wantRate: subtitle.FrameRate{Num: 30000, Den: 1001},
+		},
+		{
+			name:       "frame rate header",
+			args:       []string{"--fps", "50", "--fps-header", "input.txt"},
+			wantRate:   subtitle.FrameRate{Num: 50, Den: 1},
+			wantHeader: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArguments(tt.args)
+			if err != nil {
+				t.Fatalf("ParseArguments() unexpected error: %v", err)
+			}
+
+			if got.FrameRate != tt.wantRate {
+				t.Errorf("FrameRate = %v, want %v", got.FrameRate, tt.wantRate)
+			}
+
+			if got.FrameRateHeader != tt.wantHeader {
+				t.Errorf("FrameRateHeader = %v, want %v", got.FrameRateHeader, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestParseArguments_Shift(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantShift  subtitle.Offset
+		wantPolicy subtitle.ShiftPolicy
+	}{
+		{
+			name: "no shift",
+			args: []string{"input.srt"},
+		},
+		{
+			name:      "seconds",
+			args:      []string{"--shift", "+2.5s", "input.srt"},
+			wantShift: subtitle.Offset{Duration: 2500 * time.Millisecond},
+		},
+		{
+			name:       "clock time with policy",
+			args:       []string{"--shift", "-00:00:01,200", "--shift-policy", "drop", "input.srt"},
+			wantShift:  subtitle.Offset{Duration: -1200 * time.Millisecond},
+			wantPolicy: subtitle.ShiftDrop,
+		},
+		{
+			name:      "frames",
+			args:      []string{"--shift", "-48f", "input.txt"},
+			wantShift: subtitle.Offset{Frames: -48},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArguments(tt.args)
+			if err != nil {
+				t.Fatalf("ParseArguments() unexpected error: %v", err)
+			}
+
+			if got.Shift != tt.wantShift {
+				t.Errorf("Shift = %v, want %v", got.Shift, tt.wantShift)
+			}
+
+			if got.ShiftPolicy != tt.wantPolicy {
+				t.Errorf("ShiftPolicy = %v, want %v", got.ShiftPolicy, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestParseArguments_Sync(t *testing.T) {
+	syncFile := filepath.Join(t.TempDir(), "anchors.txt")
+
+	content := "# opening\n1 00:01:02,300\n"
+	if err := os.WriteFile(syncFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	got, err := ParseArguments([]string{
+		"--sync-file", syncFile,
+		"--sync", "812=01:43:10,000",
+		"--sync", "01:00:00,000=01:00:02,000",
+		"input.srt",
+	})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	want := []subtitle.Anchor{
+		{
+			Cue:    1,
+			Target: subtitle.Offset{Duration: time.Minute + 2300*time.Millisecond},
+		},
+		{
+			Cue: 812,
+			Target: subtitle.Offset{
+				Duration: time.Hour + 43*time.Minute + 10*time.Second,
+			},
+		},
+		{
+			Source: subtitle.Offset{Duration: time.Hour},
+			Target: subtitle.Offset{Duration: time.Hour + 2*time.Second},
+		},
+	}
+
+	if len(got.Sync) != len(want) {
+		t.Fatalf("Sync = %v, want %v", got.Sync, want)
+	}
+
+	for i := range want {
+		if got.Sync[i] != want[i] {
+			t.Errorf("Sync[%d] = %v, want %v", i, got.Sync[i], want[i])
+		}
+	}
+}
+
 func TestParseArgumentsErrors(t *testing.T) {
 	tests := []struct {
 		name string
@@ -185,6 +408,58 @@ func TestParseArgumentsErrors(t *testing.T) {
 			name: "flag without value",
 			args: []string{"-o"},
 		},
+		{
+			name: "unknown input format",
+			args: []string{"--from", "pdf"},
+		},
+		{
+			name: "unknown output format",
+			args: []string{"-t", "docx"},
+		},
+		{
+			name: "unknown format name",
+			args: []string{"-t", "unknown"},
+		},
+		{
+			name: "invalid frame rate",
+			args: []string{"--fps", "fast"},
+		},
+		{
+			name: "zero frame rate",
+			args: []string{"--fps", "0"},
+		},
+		{
+			name: "invalid shift",
+			args: []string{"--shift", "2.5"},
+		},
+		{
+			name: "unknown shift policy",
+			args: []string{"--shift", "1s", "--shift-policy", "wrap"},
+		},
+		{
+			name: "unknown display policy",
+			args: []string{"--display-policy", "forever"},
+		},
+		{
+			name: "negative display time",
+			args: []string{"--display-time", "-1s"},
+		},
+		{
+			name: "invalid frame rate conversion",
+			args: []string{"--convert-fps", "25"},
+		},
+		{
+			name: "frame rate conversion with --fps",
+			args: []string{"--convert-fps", "23.976:25", "--fps", "25"},
+		},
+		{
+			name: "invalid sync anchor",
+			args: []string{"--sync", "1"},
+		},
+		{
+			name: "missing sync file",
+			args: []string{"--sync-file", "does-not-exist.txt"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -198,6 +473,701 @@ func TestParseArgumentsErrors(t *testing.T) {
 	}
 }
 
+func TestParseArguments_UnknownFormatMessage(t *testing.T) {
+	_, err := ParseArguments([]string{"--to", "pdf"})
+	if err == nil {
+		t.Fatal("ParseArguments() expected error but got nil")
+	}
+
+	if !errors.Is(err, subtitle.ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+
+	for _, name := range subtitle.SupportedFormats() {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected supported format %q in error: %v", name, err)
+		}
+	}
+}
+
+func TestProcess_DetectsInputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		format   subtitle.FileFormat
+		want     string
+	}{
+		{
+			name:     "srt by extension",
+			fileName: "input.srt",
+			content:  "1\n00:00:01,000 --> 00:00:02,000\nText\n",
+			want:     "{24}{48}Text\n",
+		},
+		{
+			name:     "srt content in a txt file",
+			fileName: "input.txt",
+			content:  "1\n00:00:01,000 --> 00:00:02,000\nText\n",
+			want:     "{24}{48}Text\n",
+		},
+		{
+			name:     "microdvd content in a sub file",
+			fileName: "input.sub",
+			content:  "{24}{48}Text\n",
+			want:     "{24}{48}Text\n",
+		},
+		{
+			name:     "vtt content without extension",
+			fileName: "input",
+			content:  "WEBVTT\n\n00:01.000 --> 00:02.000\nText\n",
+			want:     "{24}{48}Text\n",
+		},
+		{
+			name:     "explicit format skips detection",
+			fileName: "input.srt",
+			content:  "{24}{48}Text\n",
+			format:   subtitle.TxtFormat,
+			want:     "{24}{48}Text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			input := filepath.Join(tmpDir, tt.fileName)
+			output := filepath.Join(tmpDir, "output.txt")
+
+			if err := os.WriteFile(input, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to create temp file: %v", err)
+			}
+
+			config := MainConfig{
+				InputPath:    input,
+				InputFormat:  tt.format,
+				OutputPath:   output,
+				OutputFormat: subtitle.TxtFormat,
+			}
+
+			if err := process(context.Background(), config); err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("failed to read output file: %v", err)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, string(data))
+			}
+		})
+	}
+}
+
+func TestProcess_ParseError(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "movie.txt")
+
+	content := "{1}{2}First\n{12a}{30}Second\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config := MainConfig{
+		InputPath:    input,
+		InputFormat:  subtitle.TxtFormat,
+		OutputPath:   filepath.Join(tmpDir, "output.srt"),
+		OutputFormat: subtitle.SrtFormat,
+	}
+
+	err := process(context.Background(), config)
+
+	var parseErr *subtitle.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+
+	expected := input + `:2:2: invalid start frame "12a"`
+	if parseErr.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, parseErr.Error())
+	}
+}
+
+func TestProcess_Lenient(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "movie.txt")
+	output := filepath.Join(tmpDir, "output.srt")
+
+	content := "{1}{2}First\n{12a}{30}Broken\n{48}{72}Second\n{96}x\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments([]string{"--lenient", "-o", output, input})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if !config.Lenient {
+		t.Fatal("expected --lenient to enable lenient mode")
+	}
+
+	err = process(context.Background(), config)
+
+	var skipped *subtitle.SkippedCuesError
+	if !errors.As(err, &skipped) {
+		t.Fatalf("expected SkippedCuesError, got %v", err)
+	}
+
+	if len(skipped.Errors) != 2 || skipped.Errors[0].Line != 2 ||
+		skipped.Errors[1].Line != 4 {
+		t.Errorf("unexpected skipped cues: %v", skipped)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if strings.Count(string(got), " --> ") != 2 {
+		t.Errorf("expected the 2 valid cues in output, got:\n%s", got)
+	}
+}
+
+func TestProcess_ParseErrorLeavesOutputUnfinished(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "movie.txt")
+	output := filepath.Join(tmpDir, "output.ttml")
+
+	content := "{1}{2}First\n{12a}{30}Second\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config := MainConfig{
+		InputPath:    input,
+		InputFormat:  subtitle.TxtFormat,
+		OutputPath:   output,
+		OutputFormat: subtitle.TtmlFormat,
+	}
+
+	err := process(context.Background(), config)
+
+	var parseErr *subtitle.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if strings.Contains(string(got), "</tt>") {
+		t.Errorf("expected an unfinished document, got:\n%s", got)
+	}
+}
+
+func TestProcess_LenientCloseError(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "movie.txt")
+
+	content := "{1}{2}First\n{12a}{30}Broken\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config := MainConfig{
+		InputPath:    input,
+		InputFormat:  subtitle.TxtFormat,
+		OutputPath:   filepath.Join(tmpDir, "output.ttml"),
+		OutputFormat: subtitle.ImscFormat,
+		Lenient:      true,
+	}
+
+	// IMSC1 output without a language fails on Close
+	err := process(context.Background(), config)
+
+	var skipped *subtitle.SkippedCuesError
+	if !errors.As(err, &skipped) {
+		t.Errorf("expected SkippedCuesError, got %v", err)
+	}
+
+	if !errors.Is(err, subtitle.ErrNotConformant) {
+		t.Errorf("expected ErrNotConformant, got %v", err)
+	}
+}
+
+func TestProcess_EmptyInputWritesHeader(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "empty.srt")
+	output := filepath.Join(tmpDir, "output.vtt")
+
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config := MainConfig{
+		InputPath:    input,
+		InputFormat:  subtitle.SrtFormat,
+		OutputPath:   output,
+		OutputFormat: subtitle.VttFormat,
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if string(got) != "WEBVTT\n\n" {
+		t.Errorf("expected the WebVTT header only, got %q", got)
+	}
+}
+
+func TestProcess_Shift(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.srt")
+	output := filepath.Join(tmpDir, "output.srt")
+
+	content := "1\n00:00:01,000 --> 00:00:02,000\nEarly\n\n" +
+		"2\n00:00:05,000 --> 00:00:06,000\nLate\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments(
+		[]string{"--shift", "-1.5s", "--shift-policy", "drop", "-o", output, input},
+	)
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "1\n00:00:03,500 --> 00:00:04,500\nLate\n\n"
+	if string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcess_ShiftFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "header rate",
+			expected: "1\n00:00:02,080 --> 00:00:03,080\nText\n\n",
+		},
+		{
+			name:     "converted rate",
+			args:     []string{"--convert-fps", "25:50"},
+			expected: "1\n00:00:01,040 --> 00:00:01,540\nText\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			input := filepath.Join(tmpDir, "input.txt")
+			output := filepath.Join(tmpDir, "output.srt")
+
+			content := "{1}{1}25.000\n{100}{125}Text\n"
+			if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create temp file: %v", err)
+			}
+
+			args := append([]string{"--shift", "-48f", "-o", output}, tt.args...)
+
+			config, err := ParseArguments(append(args, input))
+			if err != nil {
+				t.Fatalf("ParseArguments() unexpected error: %v", err)
+			}
+
+			if err := process(context.Background(), config); err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+
+			if string(got) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProcess_ShiftAssComments(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.ass")
+	output := filepath.Join(tmpDir, "output.ass")
+
+	content := "[Script Info]\n\n[Events]\nFormat: Layer, Start, End, Text\n" +
+		"Comment: 0,0:00:00.00,0:00:01.00,Note\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Text\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments([]string{"--shift", "1s", "-o", output, input})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	// Comments move with the dialogues and keep their place
+	expected := "Comment: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Note\n" +
+		"Dialogue: 0,0:00:02.00,0:00:03.00,Default,,0,0,0,,Text\n"
+	if !strings.HasSuffix(string(got), expected) {
+		t.Errorf("expected %q at the end of:\n%s", expected, got)
+	}
+}
+
+func TestProcess_DisplayPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.txt")
+	output := filepath.Join(tmpDir, "output.srt")
+
+	content := "00:00:01:First\n00:00:10:Second\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments([]string{
+		"--display-policy", "until-next", "--display-time", "2s",
+		"-o", output, input,
+	})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if config.DisplayPolicy != subtitle.DisplayUntilNext ||
+		config.DisplayTime != 2*time.Second {
+		t.Errorf("unexpected display settings %v, %v", config.DisplayPolicy, config.DisplayTime)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "1\n00:00:01,000 --> 00:00:10,000\nFirst\n\n" +
+		"2\n00:00:10,000 --> 00:00:12,000\nSecond\n\n"
+	if string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcess_Track(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.smi")
+	output := filepath.Join(tmpDir, "output.srt")
+
+	content := "<SAMI><HEAD><STYLE><!--\n" +
+		".ENUSCC { Name: English; lang: en-US; }\n" +
+		".KRCC { Name: Korean; lang: ko-KR; }\n" +
+		"--></STYLE></HEAD><BODY>\n" +
+		"<SYNC Start=1000><P Class=ENUSCC>Hello<P Class=KRCC>안녕\n" +
+		"<SYNC Start=2000><P Class=ENUSCC>&nbsp;<P Class=KRCC>&nbsp;\n" +
+		"</BODY></SAMI>\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments([]string{"--track", "ko-KR", "-o", output, input})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if config.Track != "ko-KR" {
+		t.Errorf("expected track %q, got %q", "ko-KR", config.Track)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "1\n00:00:01,000 --> 00:00:02,000\n안녕\n\n"
+	if string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcess_FirstTrack(t *testing.T) {
+	content := "<SAMI><HEAD><STYLE><!--\n" +
+		".ENUSCC { Name: English; lang: en-US; }\n" +
+		".KRCC { Name: Korean; lang: ko-KR; }\n" +
+		"--></STYLE></HEAD><BODY>\n" +
+		"<SYNC Start=1000><P Class=ENUSCC>Hello<P Class=KRCC>안녕\n" +
+		"<SYNC Start=2000><P Class=ENUSCC>&nbsp;<P Class=KRCC>&nbsp;\n" +
+		"</BODY></SAMI>\n"
+
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{
+			name:     "single track output",
+			output:   "output.srt",
+			expected: []string{"1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"},
+		},
+		{
+			name:     "output with tracks",
+			output:   "output.smi",
+			expected: []string{"Hello", "안녕"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			input := filepath.Join(tmpDir, "input.smi")
+			output := filepath.Join(tmpDir, tt.output)
+
+			if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create temp file: %v", err)
+			}
+
+			config, err := ParseArguments([]string{"-o", output, input})
+			if err != nil {
+				t.Fatalf("ParseArguments() unexpected error: %v", err)
+			}
+
+			if err := process(context.Background(), config); err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(string(got), expected) {
+					t.Errorf("expected %q in %q", expected, got)
+				}
+			}
+
+			if strings.HasSuffix(tt.output, ".srt") && strings.Contains(string(got), "안녕") {
+				t.Errorf("expected the first track only, got %q", got)
+			}
+		})
+	}
+}
+
+func TestProcess_ImscLanguage(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.srt")
+	output := filepath.Join(tmpDir, "output.ttml")
+
+	content := "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	// SRT carries no language, which IMSC1 requires
+	config, err := ParseArguments([]string{"-t", "imsc1", "-o", output, input})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	err = process(context.Background(), config)
+	if !errors.Is(err, subtitle.ErrNotConformant) {
+		t.Fatalf("expected ErrNotConformant, got %v", err)
+	}
+
+	// The document is not written at all
+	if got, _ := os.ReadFile(output); len(got) != 0 {
+		t.Errorf("expected no output, got %q", got)
+	}
+
+	config, err = ParseArguments(
+		[]string{"-t", "imsc1", "--lang", "en", "-o", output, input},
+	)
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if config.Language != "en" {
+		t.Errorf("expected language %q, got %q", "en", config.Language)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if !strings.Contains(string(got), `xml:lang="en">`) {
+		t.Errorf("expected xml:lang=\"en\" in %q", got)
+	}
+}
+
+func TestProcess_Sync(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.srt")
+	output := filepath.Join(tmpDir, "output.srt")
+
+	content := "1\n00:00:01,000 --> 00:00:02,000\nFirst\n\n" +
+		"2\n00:00:11,000 --> 00:00:12,000\nSecond\n\n" +
+		"3\n00:00:21,000 --> 00:00:22,000\nThird\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments([]string{
+		"--sync", "1=00:00:02,000",
+		"--sync", "3=00:00:42,000",
+		"-o", output,
+		input,
+	})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "1\n00:00:02,000 --> 00:00:04,000\nFirst\n\n" +
+		"2\n00:00:22,000 --> 00:00:24,000\nSecond\n\n" +
+		"3\n00:00:42,000 --> 00:00:44,000\nThird\n\n"
+	if string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcess_ConvertFrameRate(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.txt")
+	output := filepath.Join(tmpDir, "output.srt")
+
+	content := "{24}{48}First\n{24000}{24024}Second\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments(
+		[]string{"--convert-fps", "23.976:25", "-o", output, input},
+	)
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if config.ConvertFrom != (subtitle.FrameRate{Num: 24000, Den: 1001}) ||
+		config.ConvertTo != (subtitle.FrameRate{Num: 25, Den: 1}) {
+		t.Fatalf("unexpected conversion %v:%v", config.ConvertFrom, config.ConvertTo)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	// Frames keep their numbers at the PAL rate
+	expected := "1\n00:00:00,960 --> 00:00:01,920\nFirst\n\n" +
+		"2\n00:16:00,000 --> 00:16:00,960\nSecond\n\n"
+	if string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcess_FrameRateOverridesHeader(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.txt")
+	output := filepath.Join(tmpDir, "output.txt")
+
+	content := "{1}{1}25.000\n{30}{60}Text\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config, err := ParseArguments([]string{"--fps", "30", "-o", output, input})
+	if err != nil {
+		t.Fatalf("ParseArguments() unexpected error: %v", err)
+	}
+
+	if err := process(context.Background(), config); err != nil {
+		t.Fatalf("process() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	// Read and written at 30 fps, the frames keep their numbers
+	expected := "{1}{1}30.000\n{30}{60}Text\n"
+	if string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestProcess_UndetectableInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.dat")
+
+	if err := os.WriteFile(input, []byte("plain text\n"), 0644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	config := MainConfig{
+		InputPath:    input,
+		OutputPath:   filepath.Join(tmpDir, "output.srt"),
+		OutputFormat: subtitle.SrtFormat,
+	}
+
+	err := process(context.Background(), config)
+	if !errors.Is(err, subtitle.ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
 func TestInitReader(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,831 @@
+package subtitle
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AssEvent holds the properties of an ASS/SSA event.
+type AssEvent struct {
+	// Kind is the type of events other than Dialogue, e.g. Comment. They are
+	// not shown, so that the other formats leave them out.
+	Kind    string
+	Layer   int
+	Style   string
+	Actor   string
+	MarginL int
+	MarginR int
+	MarginV int
+	Effect  string
+	// Markup is the text with override blocks as read, written back while
+	// the text of the cue is unchanged
+	Markup string
+}
+
+const (
+	assScriptInfo = "Script Info"
+	assStylesV4P  = "V4+ Styles"
+	assStylesV4   = "V4 Styles"
+	assEvents     = "Events"
+	assDefault    = "Default"
+)
+
+var (
+	assStyleFormat = []string{
+		"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour",
+		"OutlineColour", "BackColour", "Bold", "Italic", "Underline",
+		"StrikeOut", "ScaleX", "ScaleY", "Spacing", "Angle", "BorderStyle",
+		"Outline", "Shadow", "Alignment", "MarginL", "MarginR", "MarginV",
+		"Encoding",
+	}
+	assDefaultStyle = []string{
+		assDefault, "Arial", "20", "&H00FFFFFF", "&H000000FF", "&H00000000",
+		"&H00000000", "0", "0", "0", "0", "100", "100", "0", "0", "1", "2",
+		"2", "2", "10", "10", "10", "1",
+	}
+	assEventFormat = []string{
+		"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR",
+		"MarginV", "Effect", "Text",
+	}
+	// assOtherEvents are the events read along with the cues, which are not
+	// shown
+	assOtherEvents = []string{"Comment", "Picture", "Sound", "Movie", "Command"}
+	// assDefaultInfo is written when the document has no [Script Info]
+	assDefaultInfo = []MetadataField{
+		{Value: "; Script generated by subgonverter"},
+		{Key: "ScriptType", Value: "v4.00+"},
+		{Key: "WrapStyle", Value: "0"},
+		{Key: "ScaledBorderAndShadow", Value: "yes"},
+		{Key: "PlayResX", Value: "384"},
+		{Key: "PlayResY", Value: "288"},
+	}
+	// SSA numbers alignments 1-3 (bottom), 9-11 (middle) and 5-7 (top)
+	ssaAlignment = map[string]string{
+		"1": "1", "2": "2", "3": "3",
+		"9": "4", "10": "5", "11": "6",
+		"5": "7", "6": "8", "7": "9",
+	}
+)
+
+func parseAssDuration(value string) (time.Duration, error) {
+	// Parse format: H:MM:SS.cc
+	clock, frac, found := strings.Cut(strings.TrimSpace(value), ".")
+
+	parts := strings.Split(clock, ":")
+	if !found || len(parts) != 3 || len(frac) != 2 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var d time.Duration
+
+	units := []time.Duration{
+		time.Hour,
+		time.Minute,
+		time.Second,
+		10 * time.Millisecond,
+	}
+
+	for i, part := range append(parts, frac) {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q: %w", value, err)
+		}
+
+		d += time.Duration(n) * units[i]
+	}
+
+	return d, nil
+}
+
+// splitAssFields splits a comma separated list, leaving the remainder of
+// the line in the last of n fields.
+func splitAssFields(value string, n int) []string {
+	fields := strings.SplitN(value, ",", n)
+	for i := range fields {
+		if i < n-1 {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+	}
+
+	return fields
+}
+
+// parseAssColor parses an &HBBGGRR& colour, with optional alpha, as
+// "#RRGGBB".
+func parseAssColor(value string) (string, bool) {
+	value = strings.Trim(strings.TrimSpace(value), "&")
+
+	bgr, ok := strings.CutPrefix(strings.ToUpper(value), "H")
+	if !ok || len(bgr) > 8 {
+		return "", false
+	}
+
+	if _, err := strconv.ParseUint(bgr, 16, 32); err != nil {
+		return "", false
+	}
+
+	bgr = fmt.Sprintf("%06s", bgr)
+	bgr = bgr[len(bgr)-6:]
+
+	return "#" + bgr[4:] + bgr[2:4] + bgr[:2], true
+}
+
+// applyAssOverrides applies the tags of an override block, e.g.
+// `\i1\c&H0000FF&`, to the style. Tags without a Span counterpart, such as
+// positioning, are ignored.
+func applyAssOverrides(style *Span, block string) {
+	// flag parses the 0 or 1 of \i, \b and \u, the weight of \b
+	flag := func(value string, on *bool) {
+		if n, err := strconv.Atoi(value); err == nil {
+			*on = n == 1 || n >= 600
+		}
+	}
+
+	for tag := range strings.SplitSeq(block, `\`) {
+		tag = strings.TrimSpace(tag)
+
+		switch {
+		case tag == "":
+		case strings.HasPrefix(tag, "fscx"), strings.HasPrefix(tag, "fscy"),
+			strings.HasPrefix(tag, "fsp"):
+		case strings.HasPrefix(tag, "fn"):
+			style.Font = strings.TrimSpace(tag[2:])
+		case strings.HasPrefix(tag, "fs"):
+			size, err := strconv.ParseFloat(tag[2:], 64)
+			if err == nil || tag == "fs" {
+				style.Size = int(size + 0.5)
+			}
+		case tag[0] == 'c' || strings.HasPrefix(tag, "1c"):
+			value := strings.TrimPrefix(strings.TrimPrefix(tag, "1"), "c")
+			if value == "" {
+				style.Color = ""
+			} else if color, ok := parseAssColor(value); ok {
+				style.Color = color
+			}
+		case tag[0] == 'i':
+			flag(tag[1:], &style.Italic)
+		case tag[0] == 'b':
+			flag(tag[1:], &style.Bold)
+		case tag[0] == 'u':
+			flag(tag[1:], &style.Underline)
+		case tag[0] == 'r':
+			// Reset to the style of the line
+			*style = Span{}
+		}
+	}
+}
+
+// parseAssText parses the text of an ASS event into spans, applying the
+// override blocks and converting the escapes.
+func parseAssText(markup string) []Span {
+	var (
+		spans []Span
+		style Span
+	)
+
+	escapes := strings.NewReplacer(
+		`\N`, "\n",
+		`\n`, "\n",
+		`\h`, "\u00A0",
+	)
+
+	emit := func(text string) {
+		span := style
+		span.Text = escapes.Replace(text)
+		spans = append(spans, span)
+	}
+
+	for {
+		from := strings.Index(markup, "{")
+		if from < 0 {
+			break
+		}
+
+		till := strings.Index(markup[from:], "}")
+		if till < 0 {
+			break
+		}
+
+		emit(markup[:from])
+		applyAssOverrides(&style, markup[from+1:from+till])
+		markup = markup[from+till+1:]
+	}
+
+	emit(markup)
+
+	return mergeSpans(spans)
+}
+
+// formatAssText renders the spans as ASS text, with override blocks where
+// the style changes.
+func formatAssText(spans []Span) string {
+	var (
+		b     strings.Builder
+		style Span
+	)
+
+	flag := func(tags *strings.Builder, name string, from, to bool) {
+		if from == to {
+			return
+		}
+
+		if to {
+			fmt.Fprintf(tags, `\%s1`, name)
+		} else {
+			fmt.Fprintf(tags, `\%s0`, name)
+		}
+	}
+
+	for _, span := range flattenRuby(spans) {
+		var tags strings.Builder
+
+		flag(&tags, "i", style.Italic, span.Italic)
+		flag(&tags, "b", style.Bold, span.Bold)
+		flag(&tags, "u", style.Underline, span.Underline)
+
+		if span.Color != style.Color {
+			tags.WriteString(`\c`)
+
+			if rgb, ok := colorHex(span.Color); ok {
+				fmt.Fprintf(&tags, "&H%s%s%s&", rgb[4:], rgb[2:4], rgb[:2])
+			}
+		}
+
+		if span.Font != style.Font {
+			fmt.Fprintf(&tags, `\fn%s`, span.Font)
+		}
+
+		if span.Size != style.Size {
+			tags.WriteString(`\fs`)
+
+			if span.Size != 0 {
+				tags.WriteString(strconv.Itoa(span.Size))
+			}
+		}
+
+		if tags.Len() > 0 {
+			fmt.Fprintf(&b, "{%s}", tags.String())
+		}
+
+		b.WriteString(strings.ReplaceAll(span.Text, "\n", `\N`))
+		style = span.style()
+	}
+
+	return b.String()
+}
+
+// assSpans returns the styled text of an event, voiced by its actor.
+func assSpans(markup, actor string) []Span {
+	spans := parseAssText(markup)
+	if actor != "" {
+		for i := range spans {
+			spans[i].Voice = actor
+		}
+	}
+
+	return spans
+}
+
+// assMarkup returns the text of an event with override blocks: the one read
+// while it still matches the text of the cue, so that edits to the text are
+// not lost, or else one built from the styled text.
+func assMarkup(sub Subtitle) string {
+	if sub.Ass.Markup != "" {
+		spans := assSpans(sub.Ass.Markup, sub.Ass.Actor)
+		read := Subtitle{Text: spansText(spans), Spans: styledText(spans)}
+
+		if read.Text == sub.Text && slices.Equal(read.spans(), sub.spans()) {
+			return sub.Ass.Markup
+		}
+	}
+
+	return formatAssText(sub.spans())
+}
+
+func newSubtitleFromAss(
+	value string,
+	format []string,
+) (sub Subtitle, err error) {
+	// Parse format: 0,0:00:01.00,0:00:02.00,Default,Actor,0,0,0,,Text
+	fields := strings.SplitN(value, ",", len(format))
+	if len(fields) != len(format) {
+		return sub, &ParseError{
+			Column: len(value) + 1,
+			Err: fmt.Errorf(
+				"expected %d fields, got %d",
+				len(format),
+				len(fields),
+			),
+		}
+	}
+
+	// offset is the position of the current field within value
+	offset := 0
+
+	for i, name := range format {
+		raw := fields[i]
+		column := offset + len(raw) - len(strings.TrimLeft(raw, " ")) + 1
+		offset += len(raw) + 1
+
+		field := raw
+		if i < len(format)-1 {
+			field = strings.TrimSpace(raw)
+		}
+
+		switch name {
+		case "Layer":
+			sub.Ass.Layer, err = strconv.Atoi(field)
+		case "Start":
+			sub.Start, err = parseAssDuration(field)
+		case "End":
+			sub.End, err = parseAssDuration(field)
+		case "Style":
+			sub.Ass.Style = strings.TrimPrefix(field, "*")
+		case "Name", "Actor":
+			sub.Ass.Actor = field
+		case "MarginL":
+			sub.Ass.MarginL, err = strconv.Atoi(field)
+		case "MarginR":
+			sub.Ass.MarginR, err = strconv.Atoi(field)
+		case "MarginV":
+			sub.Ass.MarginV, err = strconv.Atoi(field)
+		case "Effect":
+			sub.Ass.Effect = field
+		case "Text":
+			sub.Ass.Markup = field
+		}
+
+		if err != nil {
+			return sub, &ParseError{
+				Column: column,
+				Err:    &valueError{field: name, value: field, err: err},
+			}
+		}
+	}
+
+	// The actor is the speaker of the whole text
+	spans := assSpans(sub.Ass.Markup, sub.Ass.Actor)
+	sub.Text = spansText(spans)
+	sub.Spans = styledText(spans)
+
+	return sub, nil
+}
+
+func newStyle(value string, format []string) (Style, error) {
+	fields := splitAssFields(value, len(format))
+	if len(fields) != len(format) {
+		return Style{}, &ParseError{
+			Column: len(value) + 1,
+			Err: fmt.Errorf(
+				"expected %d style fields, got %d",
+				len(format),
+				len(fields),
+			),
+		}
+	}
+
+	style := Style{Values: make(map[string]string, len(format))}
+
+	for i, name := range format {
+		style.Values[name] = strings.TrimSpace(fields[i])
+	}
+
+	style.Name = style.Values["Name"]
+
+	return style, nil
+}
+
+// convertSsaStyle renames SSA style fields to their V4+ equivalents.
+func convertSsaStyle(style Style) Style {
+	if colour, ok := style.Values["TertiaryColour"]; ok {
+		style.Values["OutlineColour"] = colour
+		delete(style.Values, "TertiaryColour")
+	}
+
+	if alignment, ok := ssaAlignment[style.Values["Alignment"]]; ok {
+		style.Values["Alignment"] = alignment
+	}
+
+	return style
+}
+
+func parseAssFormat(value string) []string {
+	format := strings.Split(value, ",")
+	for i := range format {
+		format[i] = strings.TrimSpace(format[i])
+	}
+
+	return format
+}
+
+// assParser keeps the state of the section being read.
+type assParser struct {
+	doc         *Document
+	section     string
+	styleFormat []string
+	eventFormat []string
+}
+
+// parseInfo copies [Script Info] fields with a dedicated Document field.
+func (p *assParser) parseInfo(key, value string) error {
+	switch key {
+	case "Title":
+		p.doc.Title = value
+	case "Language":
+		p.doc.Language = value
+	case "PlayResX", "PlayResY":
+		res, err := strconv.Atoi(value)
+		if err != nil || res < 0 {
+			return &ParseError{
+				Column: 1,
+				Err:    &valueError{field: key, value: value, err: err},
+			}
+		}
+
+		if key == "PlayResX" {
+			p.doc.PlayResX = res
+		} else {
+			p.doc.PlayResY = res
+		}
+	}
+
+	return nil
+}
+
+// parseEvent parses the value of an event line with the Format fields.
+func (p *assParser) parseEvent(key, value string) (Subtitle, error) {
+	if p.eventFormat == nil {
+		return Subtitle{}, fmt.Errorf(
+			"%s before Format line",
+			strings.ToLower(key),
+		)
+	}
+
+	return newSubtitleFromAss(value, p.eventFormat)
+}
+
+// parseLine consumes a single line, returning a subtitle for event lines.
+// Events other than Dialogue keep their type in Ass.Kind.
+func (p *assParser) parseLine(line string) (sub Subtitle, ok bool, err error) {
+	trimmed := strings.TrimSpace(line)
+
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		p.section = trimmed[1 : len(trimmed)-1]
+
+		switch p.section {
+		case assScriptInfo, assStylesV4P, assStylesV4, assEvents:
+		default:
+			p.doc.Sections = append(p.doc.Sections, Section{Name: p.section})
+		}
+
+		return sub, false, nil
+	}
+
+	key, value, found := strings.Cut(trimmed, ":")
+	value = strings.TrimSpace(value)
+
+	// Columns reported for values are relative to the value, which is a
+	// suffix of the trimmed line
+	leading := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+	offset := leading + len(trimmed) - len(value)
+
+	switch p.section {
+	case "":
+		if trimmed != "" {
+			return sub, false, errors.New("missing [Script Info] section")
+		}
+
+	case assScriptInfo:
+		if trimmed == "" {
+			break
+		}
+
+		if !found || strings.HasPrefix(trimmed, ";") {
+			p.doc.Metadata = append(
+				p.doc.Metadata,
+				MetadataField{Value: trimmed},
+			)
+			break
+		}
+
+		if err := p.parseInfo(key, value); err != nil {
+			return sub, false, shiftColumn(err, offset)
+		}
+
+		p.doc.Metadata = append(
+			p.doc.Metadata,
+			MetadataField{Key: key, Value: value},
+		)
+
+	case assStylesV4P, assStylesV4:
+		switch {
+		case !found:
+		case key == "Format":
+			p.styleFormat = parseAssFormat(value)
+		case key == "Style":
+			if p.styleFormat == nil {
+				return sub, false, errors.New("style before Format line")
+			}
+
+			style, err := newStyle(value, p.styleFormat)
+			if err != nil {
+				return sub, false, shiftColumn(err, offset)
+			}
+
+			if p.section == assStylesV4 {
+				style = convertSsaStyle(style)
+			}
+
+			p.doc.Styles = append(p.doc.Styles, style)
+		}
+
+	case assEvents:
+		switch {
+		case !found:
+		case key == "Format":
+			p.eventFormat = parseAssFormat(value)
+		case key == "Dialogue" || slices.Contains(assOtherEvents, key):
+			sub, err = p.parseEvent(key, value)
+			if err != nil {
+				return sub, false, shiftColumn(err, offset)
+			}
+
+			if key != "Dialogue" {
+				sub.Ass.Kind = key
+			}
+
+			return sub, true, nil
+		}
+
+	default:
+		last := &p.doc.Sections[len(p.doc.Sections)-1]
+		last.Lines = append(last.Lines, line)
+	}
+
+	return sub, false, nil
+}
+
+func newAssSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		defer stop()
+
+		reader := &lineReader{next: next, name: config.Name}
+		errs := &errorPolicy{lenient: config.Lenient}
+		parser := &assParser{doc: config.document()}
+
+		for {
+			line, ok := reader.readLine()
+			if !ok {
+				break
+			}
+
+			sub, ok, err := parser.parseLine(line)
+			if err != nil {
+				if parseErr := reader.parseError(err); !errs.skip(parseErr) {
+					yield(Subtitle{}, parseErr)
+					return
+				}
+
+				continue
+			}
+
+			if ok && !yield(sub, nil) {
+				return
+			}
+		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading ass subtitle: %w", reader.err),
+			)
+
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+func writeAssDuration(w io.Writer, d time.Duration) error {
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	centis := (d % time.Second) / (10 * time.Millisecond)
+	_, err := fmt.Fprintf(
+		w,
+		"%d:%02d:%02d.%02d",
+		hours,
+		minutes,
+		seconds,
+		centis,
+	)
+
+	return err
+}
+
+// assStyleValues returns the values of a style in V4+ field order, filling
+// missing ones from the default style.
+func assStyleValues(style Style) []string {
+	values := make([]string, len(assStyleFormat))
+
+	for i, name := range assStyleFormat {
+		value, ok := style.Values[name]
+		if !ok {
+			value = assDefaultStyle[i]
+		}
+
+		values[i] = value
+	}
+
+	values[0] = style.Name
+
+	return values
+}
+
+// assInfoValue returns the value of a [Script Info] field with a dedicated
+// Document field or the language, falling back to the stored one when the
+// field is unset.
+func assInfoValue(doc *Document, lang string, field MetadataField) string {
+	switch field.Key {
+	case "ScriptType":
+		// Styles and events are always written in the V4+ layout
+		return "v4.00+"
+	case "Title":
+		return cmp.Or(doc.Title, field.Value)
+	case "Language":
+		return cmp.Or(lang, field.Value)
+	case "PlayResX":
+		if doc.PlayResX > 0 {
+			return strconv.Itoa(doc.PlayResX)
+		}
+	case "PlayResY":
+		if doc.PlayResY > 0 {
+			return strconv.Itoa(doc.PlayResY)
+		}
+	}
+
+	return field.Value
+}
+
+func writeAssHeader(w io.Writer, doc *Document, lang string) error {
+	info, sections := doc.metadata(AssFormat)
+	if len(info) == 0 {
+		info = assDefaultInfo
+	}
+
+	if _, err := fmt.Fprintf(w, "[%s]\n", assScriptInfo); err != nil {
+		return err
+	}
+
+	written := make(map[string]bool, len(info))
+
+	for _, field := range info {
+		var err error
+
+		if field.Key == "" {
+			_, err = fmt.Fprintln(w, field.Value)
+		} else {
+			value := assInfoValue(doc, lang, field)
+			_, err = fmt.Fprintf(w, "%s: %s\n", field.Key, value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		written[field.Key] = true
+	}
+
+	// Document fields missing from the stored ones
+	for _, key := range []string{"Title", "Language", "PlayResX", "PlayResY"} {
+		value := assInfoValue(doc, lang, MetadataField{Key: key})
+		if written[key] || value == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "%s: %s\n", key, value); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"\n[%s]\nFormat: %s\n",
+		assStylesV4P,
+		strings.Join(assStyleFormat, ", "),
+	)
+	if err != nil {
+		return err
+	}
+
+	styles := doc.Styles
+	if len(styles) == 0 {
+		styles = []Style{{Name: assDefault}}
+	}
+
+	for _, style := range styles {
+		values := strings.Join(assStyleValues(style), ",")
+		if _, err := fmt.Fprintf(w, "Style: %s\n", values); err != nil {
+			return err
+		}
+	}
+
+	for _, section := range sections {
+		if _, err := fmt.Fprintf(w, "\n[%s]\n", section.Name); err != nil {
+			return err
+		}
+
+		for _, line := range section.Lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = fmt.Fprintf(
+		w,
+		"\n[%s]\nFormat: %s\n",
+		assEvents,
+		strings.Join(assEventFormat, ", "),
+	)
+
+	return err
+}
+
+// writeAssSubtitle writes a cue as a Dialogue event, or an event of its
+// kind, in the V4+ layout.
+func writeAssSubtitle(w io.Writer, sub Subtitle) error {
+	var err error
+
+	kind := cmp.Or(sub.Ass.Kind, "Dialogue")
+	if _, err = fmt.Fprintf(w, "%s: %d,", kind, sub.Ass.Layer); err != nil {
+		return err
+	}
+
+	if err = writeAssDuration(w, sub.Start); err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprint(w, ","); err != nil {
+		return err
+	}
+
+	if err = writeAssDuration(w, sub.End); err != nil {
+		return err
+	}
+
+	style := sub.Ass.Style
+	if style == "" {
+		style = assDefault
+	}
+
+	// Name the speaker when the whole text has one
+	actor := sub.Ass.Actor
+	if actor == "" {
+		actor = commonStyle(sub.spans()).Voice
+	}
+
+	_, err = fmt.Fprintf(
+		w,
+		",%s,%s,%d,%d,%d,%s,%s\n",
+		style,
+		actor,
+		sub.Ass.MarginL,
+		sub.Ass.MarginR,
+		sub.Ass.MarginV,
+		sub.Ass.Effect,
+		assMarkup(sub),
+	)
+
+	return err
+}
+
+func newAssPrinter(writer io.Writer, config PrinterConfig) Printer {
+	return &printer{
+		writer: writer,
+		header: func(w io.Writer, _ *Subtitle) error {
+			return writeAssHeader(w, config.document(), config.language())
+		},
+		write:  writeAssSubtitle,
+		events: true,
+	}
+}
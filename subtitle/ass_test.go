@@ -0,0 +1,544 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const assInput = `[Script Info]
+; Script generated by Aegisub
+Title: Example
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,48,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+Style: Sign,Verdana,36,&H0000FFFF,&H000000FF,&H00000000,&H00000000,-1,0,0,0,100,100,0,0,1,2,0,8,10,10,10,1
+
+[Fonts]
+fontname: custom.ttf
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.50,Default,Alice,0,0,0,,Hello, {\i1}World{\i0}!\NSecond line
+Comment: 0,0:00:02.00,0:00:03.00,Default,,0,0,0,,Translator note
+Dialogue: 1,1:02:03.04,1:02:04.05,Sign,,10,20,30,Scroll up;10;20,{\pos(10,20)}Sign\htext
+`
+
+func TestNewSubtitlesIter_AssFormat(t *testing.T) {
+	var (
+		subs []Subtitle
+		doc  Document
+	)
+
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(assInput),
+		AssFormat,
+		ReaderConfig{Document: &doc},
+	)
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	if len(subs) != 3 {
+		t.Fatalf("expected 3 subtitles, got %d", len(subs))
+	}
+
+	want := []Subtitle{
+		{
+			Start: 1 * time.Second,
+			End:   2*time.Second + 500*time.Millisecond,
+			Text:  "Hello, World!\nSecond line",
+			Spans: &StyledText{
+				{Text: "Hello, ", Voice: "Alice"},
+				{Text: "World", Italic: true, Voice: "Alice"},
+				{Text: "!\nSecond line", Voice: "Alice"},
+			},
+			Ass: AssEvent{
+				Style:  "Default",
+				Actor:  "Alice",
+				Markup: `Hello, {\i1}World{\i0}!\NSecond line`,
+			},
+		},
+		{
+			Start: 2 * time.Second,
+			End:   3 * time.Second,
+			Text:  "Translator note",
+			Ass: AssEvent{
+				Kind:   "Comment",
+				Style:  "Default",
+				Markup: "Translator note",
+			},
+		},
+		{
+			Start: 1*time.Hour + 2*time.Minute + 3*time.Second + 40*time.Millisecond,
+			End:   1*time.Hour + 2*time.Minute + 4*time.Second + 50*time.Millisecond,
+			Text:  "Sign text",
+			Ass: AssEvent{
+				Layer:   1,
+				Style:   "Sign",
+				MarginL: 10,
+				MarginR: 20,
+				MarginV: 30,
+				Effect:  "Scroll up;10;20",
+				Markup:  `{\pos(10,20)}Sign\htext`,
+			},
+		},
+	}
+
+	for i := range want {
+		if !reflect.DeepEqual(subs[i], want[i]) {
+			t.Errorf("subtitle %d: expected %+v, got %+v", i, want[i], subs[i])
+		}
+	}
+
+	if doc.Format != AssFormat || doc.Title != "Example" ||
+		doc.PlayResX != 1920 || doc.PlayResY != 1080 {
+		t.Errorf("unexpected document %+v", doc)
+	}
+
+	if len(doc.Metadata) != 5 {
+		t.Errorf("expected 5 info fields, got %+v", doc.Metadata)
+	}
+
+	if len(doc.Styles) != 2 || doc.Styles[1].Name != "Sign" ||
+		doc.Styles[1].Values["Fontname"] != "Verdana" {
+		t.Errorf("unexpected styles %+v", doc.Styles)
+	}
+
+	if len(doc.Sections) != 1 || doc.Sections[0].Name != "Fonts" {
+		t.Errorf("unexpected sections %+v", doc.Sections)
+	}
+}
+
+func TestNewSubtitlesIter_AssFormat_Ssa(t *testing.T) {
+	input := `[Script Info]
+ScriptType: v4.00
+
+[V4 Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, TertiaryColour, BackColour, Bold, Italic, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, AlphaLevel, Encoding
+Style: Default,Tahoma,24,16777215,65535,255,0,0,0,1,2,3,6,30,30,12,0,0
+
+[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,0:00:01.00,0:00:02.00,*Default,Bob,0000,0000,0000,,SSA text
+Comment: Marked=0,0:00:02.00,0:00:03.00,*Default,,0000,0000,0000,,SSA note
+`
+
+	var (
+		buf bytes.Buffer
+		doc Document
+	)
+
+	printer := NewSubtitlePrinterWithConfig(
+		&buf,
+		AssFormat,
+		PrinterConfig{Document: &doc},
+	)
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(input),
+		AssFormat,
+		ReaderConfig{Document: &doc},
+	)
+
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if sub.Ass.Kind == "" && (sub.Ass.Style != "Default" || sub.Ass.Actor != "Bob" || sub.Text != "SSA text") {
+			t.Errorf("unexpected subtitle %+v", sub)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Style: Default,Tahoma,24,16777215,65535,255,0,0,0,0,0,100,100,0,0,1,2,3,8,30,30,12,0\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected converted style %q in:\n%s", expected, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "ScriptType: v4.00+\n") {
+		t.Errorf("expected V4+ script type in:\n%s", buf.String())
+	}
+
+	expected = "Comment: 0,0:00:02.00,0:00:03.00,Default,,0,0,0,,SSA note\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected V4+ comment %q in:\n%s", expected, buf.String())
+	}
+}
+
+func TestNewSubtitlesIter_AssFormat_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "missing script info",
+			input:   "Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Text",
+			wantErr: "missing [Script Info] section",
+		},
+		{
+			name:    "dialogue before format",
+			input:   "[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Text",
+			wantErr: "dialogue before Format line",
+		},
+		{
+			name:    "invalid start time",
+			input:   "[Events]\nFormat: Layer, Start, End, Text\nDialogue: 0,0:00:01,0:00:02.00,Text",
+			wantErr: `3:13: invalid Start "0:00:01"`,
+		},
+		{
+			name:    "invalid layer",
+			input:   "[Events]\nFormat: Layer, Start, End, Text\nDialogue: x,0:00:01.00,0:00:02.00,Text",
+			wantErr: `3:11: invalid Layer "x"`,
+		},
+		{
+			name:    "missing fields",
+			input:   "[Events]\nFormat: Layer, Start, End, Text\nDialogue: 0,0:00:01.00",
+			wantErr: "expected 4 fields, got 2",
+		},
+		{
+			name:    "indented line with field matching the key",
+			input:   "[Events]\nFormat: Layer, Start, End, Text\n  Dialogue: 0, 0:00:01.00,D,Text",
+			wantErr: `3:27: invalid End "D"`,
+		},
+		{
+			name:    "style before format",
+			input:   "[V4+ Styles]\nStyle: Default,Arial",
+			wantErr: "style before Format line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter := NewSubtitlesIter(strings.NewReader(tt.input), AssFormat)
+
+			count := 0
+			for _, err := range iter {
+				count++
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected %q error, got: %v", tt.wantErr, err)
+				}
+			}
+
+			if count != 1 {
+				t.Errorf("expected iterator to yield error once, got %d", count)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIterWithConfig_AssFormat_Lenient(t *testing.T) {
+	input := "[Script Info]\n\n[Events]\nFormat: Layer, Start, End, Text\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,First\n" +
+		"Dialogue: x,0:00:02.00,0:00:03.00,Broken\n" +
+		"Dialogue: 0,0:00:03.00\n" +
+		"Dialogue: 0,0:00:04.00,0:00:05.00,Second\n"
+
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(input),
+		AssFormat,
+		ReaderConfig{Lenient: true},
+	)
+
+	var (
+		texts   []string
+		skipped *SkippedCuesError
+	)
+
+	for sub, err := range iter {
+		if err != nil {
+			if !errors.As(err, &skipped) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			continue
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if len(texts) != 2 || texts[0] != "First" || texts[1] != "Second" {
+		t.Errorf("expected the valid cues, got %q", texts)
+	}
+
+	if skipped == nil || len(skipped.Errors) != 2 ||
+		skipped.Errors[0].Line != 6 || skipped.Errors[1].Line != 7 {
+		t.Errorf("expected lines 6 and 7 to be skipped, got %v", skipped)
+	}
+}
+
+func TestNewSubtitlePrinter_AssFormat_RoundTrip(t *testing.T) {
+	var (
+		first bytes.Buffer
+		doc   Document
+	)
+
+	printer := NewSubtitlePrinterWithConfig(
+		&first,
+		AssFormat,
+		PrinterConfig{Document: &doc},
+	)
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(assInput),
+		AssFormat,
+		ReaderConfig{Document: &doc},
+	)
+
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"; Script generated by Aegisub\nTitle: Example\nScriptType: v4.00+\nPlayResX: 1920\nPlayResY: 1080\n",
+		"Style: Sign,Verdana,36,&H0000FFFF,&H000000FF,&H00000000,&H00000000,-1,0,0,0,100,100,0,0,1,2,0,8,10,10,10,1\n",
+		"[Fonts]\nfontname: custom.ttf\n",
+		// Comments keep their place among the dialogues
+		"Dialogue: 0,0:00:01.00,0:00:02.50,Default,Alice,0,0,0,,Hello, {\\i1}World{\\i0}!\\NSecond line\n" +
+			"Comment: 0,0:00:02.00,0:00:03.00,Default,,0,0,0,,Translator note\n" +
+			"Dialogue: 1,1:02:03.04,1:02:04.05,Sign,,10,20,30,Scroll up;10;20,{\\pos(10,20)}Sign\\htext\n",
+	} {
+		if !strings.Contains(first.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, first.String())
+		}
+	}
+
+	// Writing the written script again must not change it
+	var second bytes.Buffer
+
+	doc = Document{}
+	printer = NewSubtitlePrinterWithConfig(
+		&second,
+		AssFormat,
+		PrinterConfig{Document: &doc},
+	)
+	iter = NewSubtitlesIterWithConfig(
+		bytes.NewReader(first.Bytes()),
+		AssFormat,
+		ReaderConfig{Document: &doc},
+	)
+
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first.String() != second.String() {
+		t.Errorf("round trip changed the script:\n%s\nvs\n%s", first.String(), second.String())
+	}
+}
+
+func TestNewSubtitlePrinter_AssFormat_FromPlainText(t *testing.T) {
+	var buf bytes.Buffer
+	printer := NewSubtitlePrinter(&buf, AssFormat)
+
+	sub := Subtitle{
+		Start: 1*time.Second + 234*time.Millisecond,
+		End:   2 * time.Second,
+		Text:  "First line\nSecond line",
+	}
+
+	if err := printer.Write(sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := buf.String()
+
+	if !strings.HasPrefix(got, "[Script Info]\n") {
+		t.Errorf("expected [Script Info] header, got:\n%s", got)
+	}
+
+	if !strings.Contains(got, "\nStyle: Default,Arial,20,") {
+		t.Errorf("expected default style, got:\n%s", got)
+	}
+
+	expected := "Dialogue: 0,0:00:01.23,0:00:02.00,Default,,0,0,0,,First line\\NSecond line\n"
+	if !strings.HasSuffix(got, expected) {
+		t.Errorf("expected %q at the end of:\n%s", expected, got)
+	}
+}
+
+func TestNewSubtitlePrinter_AssFormat_EditedText(t *testing.T) {
+	markup := `{\pos(10,20)}Hello, {\i1}World{\i0}!`
+
+	var buf bytes.Buffer
+	printer := NewSubtitlePrinter(&buf, AssFormat)
+
+	// The markup read is kept only while it matches the styled text
+	subs := []Subtitle{
+		{
+			Start: time.Second,
+			End:   2 * time.Second,
+			Text:  "Hello, World!",
+			Spans: &StyledText{{Text: "Hello, "}, {Text: "World", Italic: true}, {Text: "!"}},
+			Ass:   AssEvent{Markup: markup},
+		},
+		{
+			Start: time.Second,
+			End:   2 * time.Second,
+			Text:  "Hello, World!",
+			Spans: &StyledText{{Text: "Hello, "}, {Text: "World", Bold: true}, {Text: "!"}},
+			Ass:   AssEvent{Markup: markup},
+		},
+		{
+			Start: time.Second,
+			End:   2 * time.Second,
+			Text:  "Goodbye",
+			Ass:   AssEvent{Markup: markup},
+		},
+	}
+
+	for _, sub := range subs {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := "Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,," + markup + "\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hello, {\\b1}World{\\b0}!\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Goodbye\n"
+	if got := buf.String(); !strings.HasSuffix(got, expected) {
+		t.Errorf("expected %q at the end of:\n%s", expected, got)
+	}
+}
+
+func TestNewSubtitlesIter_AssToSrt(t *testing.T) {
+	var buf bytes.Buffer
+	printer := NewSubtitlePrinter(&buf, SrtFormat)
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(assInput), AssFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := "1\n00:00:01,000 --> 00:00:02,500\nHello, <i>World</i>!\nSecond line\n\n" +
+		"2\n01:02:03,040 --> 01:02:04,050\nSign text\n\n"
+
+	if got := buf.String(); got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
+
+func TestNewSubtitlesIter_AssToVtt(t *testing.T) {
+	var buf bytes.Buffer
+	printer := NewSubtitlePrinter(&buf, VttFormat)
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(assInput), AssFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The actor is kept as the voice of the cue
+	expected := "00:00:01.000 --> 00:00:02.500\n<v Alice>Hello, <i>World</i>!\nSecond line</v>\n\n"
+	if got := buf.String(); !strings.Contains(got, expected) {
+		t.Errorf("expected %q in:\n%s", expected, got)
+	}
+}
+
+func TestParseAssText(t *testing.T) {
+	tests := []struct {
+		markup string
+		want   []Span
+	}{
+		{
+			markup: `{\b1\c&H0000FF&}Red{\r} plain`,
+			want:   []Span{{Text: "Red", Bold: true, Color: "#FF0000"}, {Text: " plain"}},
+		},
+		{
+			markup: `{\fnArial\fs20.5\fscx120}Big{\fn\fs} {\u1}line\N{\i1}two`,
+			want: []Span{
+				{Text: "Big", Font: "Arial", Size: 21},
+				{Text: " "},
+				{Text: "line\n", Underline: true},
+				{Text: "two", Underline: true, Italic: true},
+			},
+		},
+		{
+			markup: `{\1c&H80FF8000&\b700\blur2}Blue`,
+			want:   []Span{{Text: "Blue", Bold: true, Color: "#0080FF"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.markup, func(t *testing.T) {
+			if got := parseAssText(tt.markup); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatAssText(t *testing.T) {
+	spans := []Span{
+		{Text: "Red", Bold: true, Color: "#FF0000"},
+		{Text: "\nplain "},
+		{Text: "漢", Ruby: "かん", Font: "Arial", Size: 20},
+	}
+
+	want := `{\b1\c&H0000FF&}Red{\b0\c}\Nplain {\fnArial\fs20}漢(かん)`
+	if got := formatAssText(spans); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	sub := Subtitle{Text: "Hi", Spans: &StyledText{{Text: "Hi", Voice: "Mary"}}}
+
+	var buf bytes.Buffer
+	if err := writeAssSubtitle(&buf, sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), ",Default,Mary,") {
+		t.Errorf("expected the voice as actor, got %q", buf.String())
+	}
+}
@@ -0,0 +1,122 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	sniffSize  = 4 * 1024
+	sniffLines = 16
+)
+
+var txtLinePattern = regexp.MustCompile(`^\{\d+\}\{\d*\}`)
+
+// FormatFromExtension returns the format conventionally stored in files
+// with the extension of path, or UnknownFormat.
+func FormatFromExtension(path string) FileFormat {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return UnknownFormat
+	}
+
+	// The first registered format wins an extension
+	for format, info := range Formats() {
+		if slices.Contains(info.Extensions, ext) {
+			return FileFormat(format)
+		}
+	}
+
+	return UnknownFormat
+}
+
+// isAmbiguousExtension reports whether several registered formats claim the
+// extension, which is then resolved by content.
+func isAmbiguousExtension(ext string) bool {
+	claims := 0
+
+	for _, info := range Formats() {
+		if slices.Contains(info.Extensions, ext) {
+			claims++
+		}
+	}
+
+	return claims > 1
+}
+
+// SniffFormat guesses the format from the first bytes of a file, returning
+// UnknownFormat when none of the registered sniffers match. The first line
+// matched by any format decides, formats being tried in registration order.
+func SniffFormat(head []byte) FileFormat {
+	formats := Formats()
+
+	head = bytes.TrimPrefix(head, []byte(byteOrderMark))
+	lines := strings.SplitN(string(head), "\n", sniffLines+1)
+
+	if len(lines) > sniffLines {
+		lines = lines[:sniffLines]
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		for format, info := range formats {
+			if info.Sniff != nil && info.Sniff(line) {
+				return FileFormat(format)
+			}
+		}
+	}
+
+	return UnknownFormat
+}
+
+// DetectFormat infers the format of reader from the extension of name and,
+// for stdin or extensions several formats claim, from its content. The
+// returned reader must be used instead of the given one, as it replays the
+// sniffed bytes.
+func DetectFormat(
+	name string,
+	reader io.Reader,
+) (FileFormat, io.Reader, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	format := FormatFromExtension(name)
+
+	if format != UnknownFormat && !isAmbiguousExtension(ext) {
+		return format, reader, nil
+	}
+
+	buffered := bufio.NewReaderSize(reader, sniffSize)
+
+	head, err := buffered.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) &&
+		!errors.Is(err, bufio.ErrBufferFull) {
+		return UnknownFormat, buffered, fmt.Errorf(
+			"failed to read input for format detection: %w",
+			err,
+		)
+	}
+
+	if sniffed := SniffFormat(head); sniffed != UnknownFormat {
+		return sniffed, buffered, nil
+	}
+
+	if format != UnknownFormat {
+		return format, buffered, nil
+	}
+
+	return UnknownFormat, buffered, fmt.Errorf(
+		"%w: cannot detect the format of %q",
+		ErrUnknownFormat,
+		name,
+	)
+}
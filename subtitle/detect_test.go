@@ -0,0 +1,203 @@
+package subtitle
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+// registerHeaderSrtFormat registers a toy SubRip variant with a header line,
+// sharing the .srt extension, once per test binary.
+var registerHeaderSrtFormat = sync.OnceValues(func() (FileFormat, error) {
+	return Register(Format{
+		Name:       "HeaderSrt",
+		Extensions: []string{".srt"},
+		Sniff: func(line string) bool {
+			return line == "#srt"
+		},
+		Decoder: Formats()[SrtFormat].Decoder,
+	})
+})
+
+func TestFormatFromExtension(t *testing.T) {
+	tests := []struct {
+		path string
+		want FileFormat
+	}{
+		{"movie.srt", SrtFormat},
+		{"movie.SRT", SrtFormat},
+		{"movie.txt", TxtFormat},
+		{"movie.sub", TxtFormat},
+		{"movie.vtt", VttFormat},
+		{"movie.ass", AssFormat},
+		{"movie.ssa", AssFormat},
+		{"movie.smi", SamiFormat},
+		{"movie.dfxp", TtmlFormat},
+		{"movie.xml", UnknownFormat},
+		{"dir.srt/movie", UnknownFormat},
+		{"movie.mkv", UnknownFormat},
+		{"-", UnknownFormat},
+		{"", UnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := FormatFromExtension(tt.path); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want FileFormat
+	}{
+		{"microdvd", "{1}{1}25.000\n{25}{50}Text", TxtFormat},
+		{"microdvd open ended", "{25}{}Text", TxtFormat},
+		{"srt", "1\n00:00:01,000 --> 00:00:02,000\nText", SrtFormat},
+		{"srt with blank lines and BOM", "\uFEFF\n\n1\r\n00:00:01,000 --> 00:00:02,000\r\n", SrtFormat},
+		{"vtt", "WEBVTT\n\n00:01.000 --> 00:02.000\nText", VttFormat},
+		{"vtt with BOM", "\uFEFFWEBVTT - title\n", VttFormat},
+		{"ass", "[Script Info]\nScriptType: v4.00+", AssFormat},
+		{"mpl2", "[10][25]Text|/Italic", Mpl2Format},
+		{"tmplayer", "00:00:01:Text", TmplayerFormat},
+		{"tmplayer multiline", "0:00:01,1=Text", TmplayerFormat},
+		{"subviewer", "[INFORMATION]\n[TITLE]Movie\n[END INFORMATION]", SubviewerFormat},
+		{"subviewer timing", "00:00:01.00,00:00:03.50\nText", SubviewerFormat},
+		{"ttml", "<?xml version=\"1.0\"?>\n<tt xmlns=\"http://www.w3.org/ns/ttml\">", TtmlFormat},
+		{"ttml with prefix", "<tt:tt xmlns:tt=\"http://www.w3.org/ns/ttml\">", TtmlFormat},
+		{"single-line ttml", "<?xml version=\"1.0\"?><tt xmlns=\"http://www.w3.org/ns/ttml\"><body/></tt>", TtmlFormat},
+		{"single-line ttml with spaces", "<?xml version=\"1.0\"?> <tt:tt xmlns:tt=\"http://www.w3.org/ns/ttml\">", TtmlFormat},
+		{"xml other than ttml", "<?xml version=\"1.0\"?><html><tt>code</tt></html>", UnknownFormat},
+		{"sami", "<SAMI>\n<HEAD>\n<TITLE>Movie</TITLE>", SamiFormat},
+		{"subviewer 1.0", "[TITLE]\nMovie\n**START SCRIPT**\n[00:00:01]\nText", SubviewerFormat},
+		{"plain text", "Hello\nWorld", UnknownFormat},
+		{"empty", "", UnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffFormat([]byte(tt.head)); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	const srt = "1\n00:00:01,000 --> 00:00:02,000\nText\n"
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    FileFormat
+	}{
+		{"extension wins for unambiguous formats", "movie.vtt", srt, VttFormat},
+		{"content wins for txt", "movie.txt", srt, SrtFormat},
+		{"content wins for sub", "movie.sub", "{1}{2}Text", TxtFormat},
+		{"content wins for subviewer", "movie.sub", "[INFORMATION]\n[TITLE]Movie\n", SubviewerFormat},
+		{"microdvd fallback for sub", "movie.sub", "Text", TxtFormat},
+		{"content wins for mpl2", "movie.txt", "[10][25]Text\n", Mpl2Format},
+		{"content wins for tmplayer", "movie.txt", "00:00:01:Text\n", TmplayerFormat},
+		{"txt fallback for unknown content", "movie.txt", "Text", TxtFormat},
+		{"content wins for xml", "movie.xml", "<tt xmlns=\"http://www.w3.org/ns/ttml\">", TtmlFormat},
+		{"stdin", "<stdin>", srt, SrtFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A one-byte reader cannot be rewound, so the returned reader
+			// has to replay the sniffed bytes
+			reader := iotest.OneByteReader(strings.NewReader(tt.content))
+
+			got, replay, err := DetectFormat(tt.path, reader)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+
+			data, err := io.ReadAll(replay)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(data) != tt.content {
+				t.Errorf("expected content %q, got %q", tt.content, string(data))
+			}
+		})
+	}
+}
+
+func TestDetectFormat_LargeInput(t *testing.T) {
+	content := "{1}{2}" + strings.Repeat("x", 3*sniffSize) + "\n"
+
+	got, replay, err := DetectFormat("", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != TxtFormat {
+		t.Errorf("expected %v, got %v", TxtFormat, got)
+	}
+
+	data, err := io.ReadAll(replay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(data) != content {
+		t.Errorf("expected %d bytes, got %d", len(content), len(data))
+	}
+}
+
+func TestDetectFormat_SharedExtension(t *testing.T) {
+	format, err := registerHeaderSrtFormat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A second format claiming .srt makes the extension resolved by content
+	tests := []struct {
+		content string
+		want    FileFormat
+	}{
+		{"#srt\n1\n00:00:01,000 --> 00:00:02,000\nText\n", format},
+		{"1\n00:00:01,000 --> 00:00:02,000\nText\n", SrtFormat},
+		{"Text", SrtFormat},
+	}
+
+	for _, tt := range tests {
+		got, _, err := DetectFormat("movie.srt", strings.NewReader(tt.content))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.content, tt.want, got)
+		}
+	}
+}
+
+func TestDetectFormat_Errors(t *testing.T) {
+	_, _, err := DetectFormat("<stdin>", strings.NewReader("Hello"))
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+
+	readErr := errors.New("read error")
+
+	_, _, err = DetectFormat("<stdin>", iotest.ErrReader(readErr))
+	if !errors.Is(err, readErr) {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DisplayPolicy decides when cues without an end time end, e.g. TMPlayer
+// cues or MicroDVD {start}{} cues. Such cues never overlap the next one.
+type DisplayPolicy uint8
+
+const (
+	// DisplayDefault follows the convention of the format, DisplayUntilNext
+	// for VPlayer and DisplayFixed otherwise
+	DisplayDefault DisplayPolicy = iota
+	// DisplayFixed shows cues for the display time
+	DisplayFixed
+	// DisplayUntilNext shows cues until the next one starts, the last one for
+	// the display time
+	DisplayUntilNext
+)
+
+var displayPolicyNames = [...]string{
+	DisplayDefault:   "default",
+	DisplayFixed:     "fixed",
+	DisplayUntilNext: "until-next",
+}
+
+func (p DisplayPolicy) String() string {
+	if int(p) < len(displayPolicyNames) {
+		return displayPolicyNames[p]
+	}
+
+	return fmt.Sprintf("DisplayPolicy(%d)", p)
+}
+
+// ParseDisplayPolicy returns the policy with the given name: default, fixed
+// or until-next.
+func ParseDisplayPolicy(name string) (DisplayPolicy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	for policy, policyName := range displayPolicyNames {
+		if policyName == name {
+			return DisplayPolicy(policy), nil
+		}
+	}
+
+	return DisplayDefault, fmt.Errorf(
+		"unknown display policy %q (supported: %s)",
+		name,
+		strings.Join(displayPolicyNames[:], ", "),
+	)
+}
+
+// displayTime returns the display time of cues without an end time.
+func (c ReaderConfig) displayTime() time.Duration {
+	if c.DisplayTime > 0 {
+		return c.DisplayTime
+	}
+
+	return openEndedDuration
+}
+
+// endOpenCue ends a cue without an end time, which was given the display
+// time, before the next cue starting at next. fallback is the policy of the
+// format, used unless the config sets one.
+func (c ReaderConfig) endOpenCue(
+	sub *Subtitle,
+	next time.Duration,
+	fallback DisplayPolicy,
+) {
+	if next < sub.Start {
+		return
+	}
+
+	policy := c.DisplayPolicy
+	if policy == DisplayDefault {
+		policy = fallback
+	}
+
+	if policy == DisplayUntilNext {
+		sub.End = next
+		return
+	}
+
+	sub.End = min(sub.End, next)
+}
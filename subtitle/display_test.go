@@ -0,0 +1,68 @@
+package subtitle
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDisplayPolicy(t *testing.T) {
+	for _, policy := range []DisplayPolicy{DisplayDefault, DisplayFixed, DisplayUntilNext} {
+		got, err := ParseDisplayPolicy(policy.String())
+		if err != nil || got != policy {
+			t.Errorf("%v: expected round trip, got %v, %v", policy, got, err)
+		}
+	}
+
+	if _, err := ParseDisplayPolicy("forever"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestNewSubtitlesIterWithConfig_TxtFormat_DisplayPolicy(t *testing.T) {
+	// At 25 fps, open-ended cues at 1s, 10s and 20s
+	input := "{25}{}First\n{250}{}Second\n{500}{}Last\n"
+
+	tests := []struct {
+		name   string
+		config ReaderConfig
+		ends   []time.Duration
+	}{
+		{
+			name: "default",
+			ends: []time.Duration{4 * time.Second, 13 * time.Second, 23 * time.Second},
+		},
+		{
+			name:   "until next",
+			config: ReaderConfig{DisplayPolicy: DisplayUntilNext},
+			ends:   []time.Duration{10 * time.Second, 20 * time.Second, 23 * time.Second},
+		},
+		{
+			name:   "display time",
+			config: ReaderConfig{DisplayTime: 500 * time.Millisecond},
+			ends:   []time.Duration{1500 * time.Millisecond, 10500 * time.Millisecond, 20500 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.FrameRate = FrameRate{Num: 25, Den: 1}
+
+			var ends []time.Duration
+
+			subs := NewSubtitlesIterWithConfig(strings.NewReader(input), TxtFormat, tt.config)
+			for sub, err := range subs {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				ends = append(ends, sub.End)
+			}
+
+			if !slices.Equal(ends, tt.ends) {
+				t.Errorf("expected %v, got %v", tt.ends, ends)
+			}
+		})
+	}
+}
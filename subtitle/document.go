@@ -0,0 +1,107 @@
+package subtitle
+
+// Document holds the file-level information of a subtitle file, which the
+// stream of cues does not carry. Readers fill the Document given in
+// ReaderConfig before yielding the first cue, and printers read the one
+// given in PrinterConfig when writing the header, so that sharing a single
+// Document carries it across a conversion.
+type Document struct {
+	// Format the document was read from
+	Format FileFormat
+	// Language is a language tag, e.g. "en"
+	Language string
+	// Title names the document, e.g. the Title of [Script Info] (ASS/SSA)
+	// or the text after the signature (WebVTT)
+	Title string
+	// FrameRate declared by the file, e.g. by a MicroDVD {1}{1}25 header
+	FrameRate FrameRate
+	// PlayResX and PlayResY are the script resolution (ASS/SSA)
+	PlayResX int
+	PlayResY int
+	Styles   []Style
+	Regions  []Region
+	// Tracks lists the tracks of files with several, e.g. SAMI language
+	// classes
+	Tracks []Track
+	// Metadata holds the other header fields in file order, e.g. [Script
+	// Info] entries (ASS/SSA) or header lines (WebVTT). Fields with a
+	// dedicated Document field, e.g. Title, are written from that field.
+	Metadata []MetadataField
+	// Sections holds raw sections without dedicated support, e.g. [Fonts]
+	// (ASS/SSA) or STYLE blocks (WebVTT)
+	Sections []Section
+}
+
+// MetadataField is a single "Key: Value" header entry. Comments and other
+// lines kept verbatim have no Key and the whole line in Value.
+type MetadataField struct {
+	Key   string
+	Value string
+}
+
+// Style is a named style definition with values keyed by the ASS V4+ style
+// field names, e.g. Fontname or PrimaryColour.
+type Style struct {
+	Name   string
+	Values map[string]string
+}
+
+// Region is a named screen area cues can be placed in, with settings in the
+// WebVTT syntax, e.g. "width:40% lines:3".
+type Region struct {
+	ID       string
+	Settings string
+}
+
+// Track is a set of cues in one language, e.g. a SAMI language class.
+type Track struct {
+	// ID is the Subtitle.Sami.Track of its cues, e.g. "ENUSCC"
+	ID string
+	// Name is a human-readable name, e.g. "English"
+	Name     string
+	Language string
+}
+
+// Section holds the raw lines of a format-specific section.
+type Section struct {
+	Name  string
+	Lines []string
+}
+
+// document returns the Document to fill, a throwaway one when none is set.
+func (c ReaderConfig) document() *Document {
+	if c.Document == nil {
+		return &Document{}
+	}
+
+	return c.Document
+}
+
+// document returns the Document to write, an empty one when none is set.
+func (c PrinterConfig) document() *Document {
+	if c.Document == nil {
+		return &Document{}
+	}
+
+	return c.Document
+}
+
+// language returns the language to write, the configured one before the
+// Document one.
+func (c PrinterConfig) language() string {
+	if c.Language != "" {
+		return c.Language
+	}
+
+	return c.document().Language
+}
+
+// metadata returns the format-specific metadata and sections, which are only
+// meaningful to writers of the format the document was read from.
+func (d *Document) metadata(format FileFormat) ([]MetadataField, []Section) {
+	if d.Format != format {
+		return nil, nil
+	}
+
+	return d.Metadata, d.Sections
+}
@@ -0,0 +1,231 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// convert reads input in one format and writes it in another, sharing a
+// Document between the reader and the printer.
+func convert(
+	t *testing.T,
+	input string,
+	from, to FileFormat,
+	doc *Document,
+) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(
+		&buf,
+		to,
+		PrinterConfig{Document: doc},
+	)
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(input),
+		from,
+		ReaderConfig{Document: doc},
+	)
+
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return buf.String()
+}
+
+func TestDocument_TxtFrameRate(t *testing.T) {
+	input := "{1}{1}25.000\n{25}{50}First\n{75}{100}Second\n"
+
+	var doc Document
+	if got := convert(t, input, TxtFormat, TxtFormat, &doc); got != input {
+		t.Errorf("expected %q, got %q", input, got)
+	}
+
+	if doc.Format != TxtFormat || doc.FrameRate != (FrameRate{Num: 25, Den: 1}) {
+		t.Errorf("unexpected document %+v", doc)
+	}
+}
+
+func TestDocument_FrameRateFromOtherFormats(t *testing.T) {
+	input := `<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" ttp:frameRate="25">
+<body><div><p begin="1s" end="2s">Text</p></div></body></tt>`
+
+	// Without a header the frames are counted at the default rate
+	var doc Document
+	if got := convert(t, input, TtmlFormat, TxtFormat, &doc); got != "{24}{48}Text\n" {
+		t.Errorf("expected no header, got %q", got)
+	}
+
+	if doc.FrameRate != (FrameRate{Num: 25, Den: 1}) {
+		t.Errorf("unexpected document %+v", doc)
+	}
+
+	// The header declares the rate of the document
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(&buf, TxtFormat, PrinterConfig{Document: &doc, FrameRateHeader: true})
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), TtmlFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := buf.String(), "{1}{1}25.000\n{25}{50}Text\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDocument_Vtt(t *testing.T) {
+	input := "WEBVTT - Example\nKind: captions\nLanguage: en\n" +
+		"X-TIMESTAMP-MAP=LOCAL:00:00:00.000,MPEGTS:900000\n\n" +
+		"REGION\nid:fred width:40%\nlines:3\n\n" +
+		"STYLE\n::cue {\n  color: yellow;\n}\n\n" +
+		"00:01.000 --> 00:02.000 region:fred\nText\n\n"
+
+	var doc Document
+
+	got := convert(t, input, VttFormat, VttFormat, &doc)
+
+	expected := "WEBVTT - Example\nKind: captions\nLanguage: en\n" +
+		"X-TIMESTAMP-MAP=LOCAL:00:00:00.000,MPEGTS:900000\n\n" +
+		"REGION\nid:fred\nwidth:40%\nlines:3\n\n" +
+		"STYLE\n::cue {\n  color: yellow;\n}\n\n" +
+		"00:00:01.000 --> 00:00:02.000 region:fred\nText\n\n"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if doc.Title != "- Example" || doc.Language != "en" || len(doc.Regions) != 1 ||
+		doc.Regions[0] != (Region{ID: "fred", Settings: "width:40% lines:3"}) {
+		t.Errorf("unexpected document %+v", doc)
+	}
+}
+
+func TestDocument_AcrossFormats(t *testing.T) {
+	input := "[Script Info]\nTitle: Example\nLanguage: pl\nPlayResX: 1280\n" +
+		"PlayResY: 720\n\n[Events]\nFormat: Layer, Start, End, Text\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Text\n"
+
+	var doc Document
+
+	// Only the title and the language apply to WebVTT
+	got := convert(t, input, AssFormat, VttFormat, &doc)
+
+	expected := "WEBVTT Example\nLanguage: pl\n\n00:00:01.000 --> 00:00:02.000\nText\n\n"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	// Dedicated fields replace the default [Script Info] values
+	doc = Document{Format: SrtFormat, Title: "Converted", PlayResX: 1920}
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nText\n"
+
+	got = convert(t, srt, SrtFormat, AssFormat, &doc)
+
+	for _, expected := range []string{
+		"PlayResX: 1920\nPlayResY: 288\nTitle: Converted\n",
+		"; Script generated by subgonverter\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+}
+
+func TestDocument_UpdatedTitle(t *testing.T) {
+	input := "[Script Info]\nTitle: Example\nScriptType: v4.00+\n\n" +
+		"[Events]\nFormat: Layer, Start, End, Text\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Text\n"
+
+	doc := Document{}
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(input),
+		AssFormat,
+		ReaderConfig{Document: &doc},
+	)
+
+	var subs []Subtitle
+
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	doc.Title = "Changed"
+
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(
+		&buf,
+		AssFormat,
+		PrinterConfig{Document: &doc},
+	)
+	for _, sub := range subs {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := "[Script Info]\nTitle: Changed\nScriptType: v4.00+\n"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("expected %q at the start of:\n%s", expected, buf.String())
+	}
+}
+
+func TestPrinterConfig_Language(t *testing.T) {
+	tests := []struct {
+		name   string
+		format FileFormat
+		want   string
+	}{
+		{name: "vtt", format: VttFormat, want: "Language: ko-KR\n"},
+		{name: "ass", format: AssFormat, want: "Language: ko-KR\n"},
+		{name: "sami", format: SamiFormat, want: "lang: ko-KR;"},
+		{name: "ttml", format: TtmlFormat, want: `xml:lang="ko-KR"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			// The configured language replaces the one of the document
+			doc := &Document{Language: "en"}
+			printer := NewSubtitlePrinterWithConfig(
+				&buf,
+				tt.format,
+				PrinterConfig{Document: doc, Language: "ko-KR"},
+			)
+			if err := printer.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); !strings.Contains(got, tt.want) {
+				t.Errorf("expected %q in:\n%s", tt.want, got)
+			}
+		})
+	}
+}
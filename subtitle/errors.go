@@ -0,0 +1,114 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseError describes malformed input, pointing at the line and column of
+// the problem, e.g. movie.txt:1532:2: invalid start frame "12a".
+type ParseError struct {
+	// Source names the input, e.g. its path, and may be empty
+	Source string
+	// Line and Column are 1-based, Column counts bytes
+	Line   int
+	Column int
+	// Text is the offending line
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%d:%d: %v", e.Line, e.Column, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %v", e.Source, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// valueError reports an invalid value of a named field, while errors.Is
+// still matches the underlying cause.
+type valueError struct {
+	field string
+	value string
+	err   error
+}
+
+func (e *valueError) Error() string {
+	return fmt.Sprintf("invalid %s %q", e.field, e.value)
+}
+
+func (e *valueError) Unwrap() error {
+	return e.err
+}
+
+// shiftColumn moves the column of a ParseError by offset, so that columns
+// relative to a part of the line become relative to the whole line.
+func shiftColumn(err error, offset int) error {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Column += offset
+	}
+
+	return err
+}
+
+// SkippedCuesError reports every malformed cue skipped in lenient mode. It is
+// yielded once, after all the cues that could be read.
+type SkippedCuesError struct {
+	Errors []*ParseError
+}
+
+func (e *SkippedCuesError) Error() string {
+	lines := make([]string, 0, len(e.Errors)+1)
+	lines = append(
+		lines,
+		fmt.Sprintf("skipped %d malformed cue(s):", len(e.Errors)),
+	)
+
+	for _, err := range e.Errors {
+		lines = append(lines, err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (e *SkippedCuesError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// errorPolicy stops at the first parse error or, in lenient mode, collects
+// the errors for a final SkippedCuesError.
+type errorPolicy struct {
+	lenient bool
+	skipped []*ParseError
+}
+
+// skip reports whether parsing may continue past err.
+func (p *errorPolicy) skip(err *ParseError) bool {
+	if !p.lenient {
+		return false
+	}
+
+	p.skipped = append(p.skipped, err)
+
+	return true
+}
+
+func (p *errorPolicy) report() error {
+	if len(p.skipped) == 0 {
+		return nil
+	}
+
+	return &SkippedCuesError{Errors: p.skipped}
+}
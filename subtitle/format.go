@@ -0,0 +1,358 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Decoder reads the subtitles of one format from a stream.
+type Decoder interface {
+	Decode(reader io.Reader, config ReaderConfig) iter.Seq2[Subtitle, error]
+}
+
+// DecoderFunc adapts a function to the Decoder interface.
+type DecoderFunc func(
+	reader io.Reader,
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error]
+
+func (f DecoderFunc) Decode(
+	reader io.Reader,
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return f(reader, config)
+}
+
+// Encoder writes the subtitles of one format to a stream.
+type Encoder interface {
+	Encode(writer io.Writer, config PrinterConfig) Printer
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(writer io.Writer, config PrinterConfig) Printer
+
+func (f EncoderFunc) Encode(writer io.Writer, config PrinterConfig) Printer {
+	return f(writer, config)
+}
+
+// Format describes a subtitle format known to the registry.
+type Format struct {
+	// Name is the lower-case name accepted by ParseFileFormat, e.g. "srt"
+	Name string
+	// Aliases are alternative names, e.g. "subrip"
+	Aliases []string
+	// Extensions conventionally used by the format, e.g. ".srt"
+	Extensions []string
+	// Sniff reports whether a trimmed, non-blank line from the head of a file
+	// identifies the format; may be nil
+	Sniff func(line string) bool
+	// Tracks reports whether the encoder writes several tracks, e.g. SAMI
+	// language classes
+	Tracks bool
+	// Decoder and Encoder may be nil for read-only or write-only formats
+	Decoder Decoder
+	Encoder Encoder
+}
+
+type FileFormat uint8
+
+const (
+	UnknownFormat FileFormat = iota
+	TxtFormat
+	SrtFormat
+	VttFormat
+	AssFormat
+	Mpl2Format
+	TmplayerFormat
+	VplayerFormat
+	SubviewerFormat
+	SamiFormat
+	TtmlFormat
+	ImscFormat
+)
+
+var ErrFormatRegistered = errors.New("format already registered")
+
+var (
+	registryMu sync.RWMutex
+	// registry is indexed by FileFormat, in registration order
+	registry = []Format{
+		UnknownFormat: {Name: "unknown"},
+		TxtFormat: {
+			Name:       "txt",
+			Aliases:    []string{"microdvd"},
+			Extensions: []string{".txt", ".sub"},
+			Sniff:      txtLinePattern.MatchString,
+			Decoder:    lineDecoder(newTxtSubtitlesIter),
+			Encoder:    EncoderFunc(newTxtPrinter),
+		},
+		SrtFormat: {
+			Name:       "srt",
+			Aliases:    []string{"subrip"},
+			Extensions: []string{".srt"},
+			Sniff: func(line string) bool {
+				return strings.Contains(line, "-->")
+			},
+			Decoder: lineDecoder(newSrtSubtitlesIter),
+			Encoder: EncoderFunc(newSrtPrinter),
+		},
+		VttFormat: {
+			Name:       "vtt",
+			Aliases:    []string{"webvtt"},
+			Extensions: []string{".vtt"},
+			Sniff: func(line string) bool {
+				return isVttKeyword(line, vttSignature)
+			},
+			Decoder: lineDecoder(newVttSubtitlesIter),
+			Encoder: EncoderFunc(newVttPrinter),
+		},
+		AssFormat: {
+			Name:       "ass",
+			Aliases:    []string{"ssa"},
+			Extensions: []string{".ass", ".ssa"},
+			Sniff: func(line string) bool {
+				return strings.EqualFold(line, "["+assScriptInfo+"]")
+			},
+			Decoder: lineDecoder(newAssSubtitlesIter),
+			Encoder: EncoderFunc(newAssPrinter),
+		},
+		Mpl2Format: {
+			Name:       "mpl2",
+			Aliases:    []string{"mpl"},
+			Extensions: []string{".txt"},
+			Sniff:      mpl2LinePattern.MatchString,
+			Decoder:    lineDecoder(newMpl2SubtitlesIter),
+			Encoder:    EncoderFunc(newMpl2Printer),
+		},
+		TmplayerFormat: {
+			Name:       "tmplayer",
+			Extensions: []string{".txt"},
+			Sniff:      clockLinePattern.MatchString,
+			Decoder:    lineDecoder(newTmplayerSubtitlesIter),
+			Encoder:    EncoderFunc(newClockPrinter),
+		},
+		// VPlayer lines cannot be told from TMPlayer ones, so VPlayer is
+		// neither sniffed nor claims an extension, and is only read when
+		// selected by name
+		VplayerFormat: {
+			Name:    "vplayer",
+			Decoder: lineDecoder(newVplayerSubtitlesIter),
+			Encoder: EncoderFunc(newClockPrinter),
+		},
+		// A .sub file is MicroDVD unless its content says otherwise
+		SubviewerFormat: {
+			Name:       "subviewer",
+			Extensions: []string{".sub"},
+			Sniff:      isSubviewerLine,
+			Decoder:    lineDecoder(newSubviewerSubtitlesIter),
+			Encoder:    EncoderFunc(newSubviewerPrinter),
+		},
+		SamiFormat: {
+			Name:       "sami",
+			Aliases:    []string{"smi"},
+			Extensions: []string{".smi", ".sami"},
+			Sniff: func(line string) bool {
+				return len(line) >= 5 && strings.EqualFold(line[:5], "<sami")
+			},
+			Tracks:  true,
+			Decoder: lineDecoder(newSamiSubtitlesIter),
+			Encoder: EncoderFunc(newSamiPrinter),
+		},
+		// Generic .xml files are detected as TTML by their content only
+		TtmlFormat: {
+			Name:       "ttml",
+			Aliases:    []string{"dfxp"},
+			Extensions: []string{".ttml", ".dfxp"},
+			Sniff:      ttmlSniffPattern.MatchString,
+			Decoder:    DecoderFunc(newTtmlSubtitlesIter),
+			Encoder:    EncoderFunc(newTtmlPrinter),
+		},
+		// IMSC1 documents are TTML, and detected as such when read
+		ImscFormat: {
+			Name:    "imsc1",
+			Aliases: []string{"imsc"},
+			Decoder: DecoderFunc(newTtmlSubtitlesIter),
+			Encoder: EncoderFunc(newImscPrinter),
+		},
+	}
+)
+
+// lineDecoder adapts a line-based iterator to the Decoder interface.
+func lineDecoder(
+	newIter func(
+		next func() (string, error, bool),
+		stop func(),
+		config ReaderConfig,
+	) iter.Seq2[Subtitle, error],
+) Decoder {
+	return DecoderFunc(
+		func(reader io.Reader, config ReaderConfig) iter.Seq2[Subtitle, error] {
+			next, stop := newScannerPull(reader)
+
+			return newIter(next, stop, config)
+		},
+	)
+}
+
+// normalizeExtension lower-cases ext and adds the leading dot if missing.
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	return ext
+}
+
+// findFormat returns the format with the given name or alias. The caller
+// must hold registryMu.
+func findFormat(name string) (FileFormat, bool) {
+	for format, info := range registry {
+		if FileFormat(format) == UnknownFormat {
+			continue
+		}
+
+		if info.Name == name {
+			return FileFormat(format), true
+		}
+	}
+
+	for format, info := range registry {
+		for _, alias := range info.Aliases {
+			if alias == name {
+				return FileFormat(format), true
+			}
+		}
+	}
+
+	return UnknownFormat, false
+}
+
+// Register adds a format to the registry and returns its FileFormat, which
+// then works with ParseFileFormat, format detection, NewSubtitlesIter and
+// NewSubtitlePrinter. Names and aliases are case-insensitive and must not
+// clash with those already registered. Formats are typically registered from
+// an init function.
+func Register(format Format) (FileFormat, error) {
+	format.Name = strings.ToLower(strings.TrimSpace(format.Name))
+	if format.Name == "" {
+		return UnknownFormat, errors.New("format name is empty")
+	}
+
+	if format.Decoder == nil && format.Encoder == nil {
+		return UnknownFormat, fmt.Errorf(
+			"format %q has neither a decoder nor an encoder",
+			format.Name,
+		)
+	}
+
+	names := make([]string, 0, len(format.Aliases)+1)
+	names = append(names, format.Name)
+
+	aliases := make([]string, len(format.Aliases))
+	for i, alias := range format.Aliases {
+		aliases[i] = strings.ToLower(strings.TrimSpace(alias))
+		names = append(names, aliases[i])
+	}
+
+	extensions := make([]string, len(format.Extensions))
+	for i, ext := range format.Extensions {
+		extensions[i] = normalizeExtension(ext)
+	}
+
+	format.Aliases = aliases
+	format.Extensions = extensions
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, name := range names {
+		if _, found := findFormat(name); found || name == "unknown" {
+			return UnknownFormat, fmt.Errorf(
+				"%w: %q",
+				ErrFormatRegistered,
+				name,
+			)
+		}
+	}
+
+	if len(registry) > math.MaxUint8 {
+		return UnknownFormat, errors.New("too many registered formats")
+	}
+
+	registry = append(registry, format)
+
+	return FileFormat(len(registry) - 1), nil
+}
+
+// Formats returns the registered formats indexed by FileFormat, the first
+// being the placeholder for UnknownFormat.
+func Formats() []Format {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]Format(nil), registry...)
+}
+
+// lookupFormat returns the registry entry of f.
+func lookupFormat(f FileFormat) (Format, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if f == UnknownFormat || int(f) >= len(registry) {
+		return Format{}, false
+	}
+
+	return registry[f], true
+}
+
+func (f FileFormat) String() string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if int(f) < len(registry) {
+		return registry[f].Name
+	}
+
+	return fmt.Sprintf("FileFormat(%d)", f)
+}
+
+// SupportedFormats lists the names accepted by ParseFileFormat, aliases
+// excluded.
+func SupportedFormats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry)-1)
+	for _, format := range registry[UnknownFormat+1:] {
+		names = append(names, format.Name)
+	}
+
+	return names
+}
+
+// ParseFileFormat returns the format with the given case-insensitive name or
+// alias, e.g. "srt" or "subrip".
+func ParseFileFormat(name string) (FileFormat, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	registryMu.RLock()
+	format, found := findFormat(name)
+	registryMu.RUnlock()
+
+	if found {
+		return format, nil
+	}
+
+	return UnknownFormat, fmt.Errorf(
+		"%w %q (supported: %s)",
+		ErrUnknownFormat,
+		name,
+		strings.Join(SupportedFormats(), ", "),
+	)
+}
@@ -0,0 +1,212 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// registerLinesFormat registers a toy format with one cue per second and
+// per line, once per test binary.
+var registerLinesFormat = sync.OnceValues(func() (FileFormat, error) {
+	return Register(Format{
+		Name:       "Lines",
+		Aliases:    []string{"one-per-line"},
+		Extensions: []string{"LNS"},
+		Sniff: func(line string) bool {
+			return line == "#lines"
+		},
+		Decoder: DecoderFunc(
+			func(reader io.Reader, _ ReaderConfig) iter.Seq2[Subtitle, error] {
+				return func(yield func(Subtitle, error) bool) {
+					data, err := io.ReadAll(reader)
+					if err != nil {
+						yield(Subtitle{}, err)
+						return
+					}
+
+					lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+					for i, line := range lines[1:] {
+						sub := Subtitle{
+							Start: time.Duration(i) * time.Second,
+							End:   time.Duration(i+1) * time.Second,
+							Text:  line,
+						}
+						if !yield(sub, nil) {
+							return
+						}
+					}
+				}
+			},
+		),
+		Encoder: EncoderFunc(func(writer io.Writer, _ PrinterConfig) Printer {
+			return &linesPrinter{writer: writer}
+		}),
+	})
+})
+
+// linesPrinter writes the toy format with a header line.
+type linesPrinter struct {
+	writer io.Writer
+	header bool
+}
+
+func (p *linesPrinter) Write(sub Subtitle) error {
+	if !p.header {
+		if _, err := fmt.Fprintln(p.writer, "#lines"); err != nil {
+			return err
+		}
+
+		p.header = true
+	}
+
+	_, err := fmt.Fprintln(p.writer, sub.Text)
+
+	return err
+}
+
+func (p *linesPrinter) Flush() error { return nil }
+
+func (p *linesPrinter) Close() error { return nil }
+
+func (p *linesPrinter) Abort() {}
+
+func TestRegister(t *testing.T) {
+	format, err := registerLinesFormat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if format <= AssFormat || format.String() != "lines" {
+		t.Errorf("unexpected registered format %d %q", format, format)
+	}
+
+	for _, name := range []string{"lines", "LINES", "one-per-line"} {
+		if got, err := ParseFileFormat(name); err != nil || got != format {
+			t.Errorf("%s: expected %v, got %v, %v", name, format, got, err)
+		}
+	}
+
+	if got := FormatFromExtension("movie.lns"); got != format {
+		t.Errorf("expected %v from extension, got %v", format, got)
+	}
+
+	if got := SniffFormat([]byte("\n#lines\nFirst\n")); got != format {
+		t.Errorf("expected %v from content, got %v", format, got)
+	}
+
+	// Convert from SubRip to the registered format and back
+	input := "1\n00:00:00,000 --> 00:00:01,000\nFirst\n\n" +
+		"2\n00:00:01,000 --> 00:00:02,000\nSecond\n\n"
+
+	var lines bytes.Buffer
+	printer := NewSubtitlePrinter(&lines, format)
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), SrtFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if expected := "#lines\nFirst\nSecond\n"; lines.String() != expected {
+		t.Errorf("expected %q, got %q", expected, lines.String())
+	}
+
+	var srt bytes.Buffer
+	printer = NewSubtitlePrinter(&srt, SrtFormat)
+
+	for sub, err := range NewSubtitlesIter(&lines, format) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if srt.String() != input {
+		t.Errorf("expected %q, got %q", input, srt.String())
+	}
+}
+
+func TestRegister_Errors(t *testing.T) {
+	encoder := EncoderFunc(
+		func(io.Writer, PrinterConfig) Printer { return nil },
+	)
+
+	tests := []struct {
+		name    string
+		format  Format
+		wantErr error
+	}{
+		{
+			name:   "empty name",
+			format: Format{Name: " ", Encoder: encoder},
+		},
+		{
+			name:   "no decoder nor encoder",
+			format: Format{Name: "nothing"},
+		},
+		{
+			name:    "duplicate name",
+			format:  Format{Name: "SRT", Encoder: encoder},
+			wantErr: ErrFormatRegistered,
+		},
+		{
+			name:    "duplicate alias",
+			format:  Format{Name: "other", Aliases: []string{"webvtt"}, Encoder: encoder},
+			wantErr: ErrFormatRegistered,
+		},
+		{
+			name:    "reserved name",
+			format:  Format{Name: "unknown", Encoder: encoder},
+			wantErr: ErrFormatRegistered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Register(tt.format)
+			if err == nil {
+				t.Fatalf("expected error, got format %v", got)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if got != UnknownFormat {
+				t.Errorf("expected UnknownFormat, got %v", got)
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	formats := Formats()
+
+	for _, format := range []FileFormat{TxtFormat, SrtFormat, VttFormat, AssFormat} {
+		info := formats[format]
+		if info.Name != format.String() || info.Decoder == nil ||
+			info.Encoder == nil {
+			t.Errorf("%v: incomplete registry entry %+v", format, info)
+		}
+	}
+
+	// The returned slice is a copy
+	formats[SrtFormat].Name = "changed"
+	if SrtFormat.String() != "srt" {
+		t.Error("expected Formats to return a copy")
+	}
+}
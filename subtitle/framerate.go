@@ -0,0 +1,212 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrameRate is a frame rate expressed as the rational number Num/Den frames
+// per second, e.g. 24000/1001 for NTSC film.
+type FrameRate struct {
+	Num int64
+	Den int64
+}
+
+// DefaultFrameRate is used by frame-based formats when no rate is configured.
+var DefaultFrameRate = FrameRate{Num: 24000, Den: 1001}
+
+// ntscBases are the integer rates with a common 1000/1001 NTSC variant.
+var ntscBases = []int64{24, 30, 48, 60, 120}
+
+var ErrInvalidFrameRate = errors.New("invalid frame rate")
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// ParseFrameRate parses a rate given either as a fraction ("24000/1001") or
+// a decimal number ("25", "29.97"). Decimal NTSC rates such as 23.976 or
+// 59.94 are mapped to their exact n*1000/1001 value.
+func ParseFrameRate(value string) (FrameRate, error) {
+	value = strings.TrimSpace(value)
+
+	if num, den, found := strings.Cut(value, "/"); found {
+		// Limit both terms to 32 bits, so that conversions cannot overflow
+		n, errNum := strconv.ParseInt(num, 10, 32)
+		d, errDen := strconv.ParseInt(den, 10, 32)
+
+		if errNum != nil || errDen != nil || n <= 0 || d <= 0 {
+			return FrameRate{}, fmt.Errorf("%w %q", ErrInvalidFrameRate, value)
+		}
+
+		return FrameRate{Num: n, Den: d}.reduce(), nil
+	}
+
+	fps, err := strconv.ParseFloat(value, 64)
+	if err != nil || fps <= 0 || fps > 1000 || math.IsNaN(fps) {
+		return FrameRate{}, fmt.Errorf("%w %q", ErrInvalidFrameRate, value)
+	}
+
+	for _, base := range ntscBases {
+		if math.Abs(fps-float64(base*1000)/1001) < 0.005 {
+			return FrameRate{Num: base * 1000, Den: 1001}, nil
+		}
+	}
+
+	rate := FrameRate{Num: int64(math.Round(fps * 1000)), Den: 1000}
+
+	return rate.reduce(), nil
+}
+
+func (r FrameRate) reduce() FrameRate {
+	if d := gcd(r.Num, r.Den); d > 1 {
+		r.Num /= d
+		r.Den /= d
+	}
+
+	return r
+}
+
+// IsZero reports whether the rate is unset.
+func (r FrameRate) IsZero() bool {
+	return r.Num == 0 || r.Den == 0
+}
+
+func (r FrameRate) orDefault() FrameRate {
+	if r.IsZero() {
+		return DefaultFrameRate
+	}
+
+	return r
+}
+
+// String formats the rate as a decimal with three fractional digits, the
+// form used by the MicroDVD frame rate header.
+func (r FrameRate) String() string {
+	if r.IsZero() {
+		return "0.000"
+	}
+
+	return strconv.FormatFloat(float64(r.Num)/float64(r.Den), 'f', 3, 64)
+}
+
+// Rounding selects how conversions round results falling between two whole
+// values.
+type Rounding uint8
+
+const (
+	// RoundNearest rounds to the nearest value, halves away from zero
+	RoundNearest Rounding = iota
+	// RoundDown rounds towards negative infinity
+	RoundDown
+	// RoundUp rounds towards positive infinity
+	RoundUp
+)
+
+// Duration returns the start time of a frame, rounded to the nearest
+// nanosecond.
+func (r FrameRate) Duration(frame int64) time.Duration {
+	return time.Duration(
+		mulDiv(frame, r.Den*int64(time.Second), r.Num, RoundNearest),
+	)
+}
+
+// Frame returns the number of the frame shown at d, or the nearest frame
+// boundary with RoundNearest.
+func (r FrameRate) Frame(d time.Duration, rounding Rounding) int64 {
+	return mulDiv(int64(d), r.Num, r.Den*int64(time.Second), rounding)
+}
+
+// frameToDuration converts a frame number to a duration rounded to the
+// nearest millisecond. Up to 1000 fps the error stays below half a frame,
+// so that durationToFrame restores the frame from millisecond timestamps.
+func (r FrameRate) frameToDuration(frame int64) time.Duration {
+	perSecond := int64(time.Second / time.Millisecond)
+	millis := mulDiv(frame, r.Den*perSecond, r.Num, RoundNearest)
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// durationToFrame converts a duration to the nearest frame number.
+func (r FrameRate) durationToFrame(d time.Duration) int64 {
+	return r.Frame(d, RoundNearest)
+}
+
+// mulDiv returns x*num/den rounded as requested, computing the product
+// exactly and saturating at the int64 limits.
+func mulDiv(x, num, den int64, rounding Rounding) int64 {
+	if den < 0 {
+		num, den = -num, -den
+	}
+
+	n := new(big.Int).Mul(big.NewInt(x), big.NewInt(num))
+	d := big.NewInt(den)
+
+	// Euclidean division rounds down, leaving 0 <= m < den
+	q, m := new(big.Int).DivMod(n, d, new(big.Int))
+
+	if m.Sign() != 0 {
+		half := m.Cmp(new(big.Int).Sub(d, m))
+
+		switch rounding {
+		case RoundUp:
+			q.Add(q, big.NewInt(1))
+		case RoundNearest:
+			if half > 0 || (half == 0 && n.Sign() > 0) {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+
+	switch {
+	case q.IsInt64():
+		return q.Int64()
+	case q.Sign() < 0:
+		return math.MinInt64
+	default:
+		return math.MaxInt64
+	}
+}
+
+// ConvertFrameRate rescales cue times from one frame rate to another, so
+// that each frame keeps its number, e.g. the PAL speedup from 23.976 to 25
+// shortens every time by 4%. Errors of subs are passed on.
+func ConvertFrameRate(
+	subs iter.Seq2[Subtitle, error],
+	from, to FrameRate,
+) iter.Seq2[Subtitle, error] {
+	from, to = from.orDefault(), to.orDefault()
+
+	// t * from / to, as a reduced fraction
+	num, den := from.Num*to.Den, from.Den*to.Num
+	if d := gcd(num, den); d > 1 {
+		num, den = num/d, den/d
+	}
+
+	return func(yield func(Subtitle, error) bool) {
+		for sub, err := range subs {
+			if err == nil {
+				sub.Start = time.Duration(
+					mulDiv(int64(sub.Start), num, den, RoundNearest),
+				)
+				sub.End = time.Duration(
+					mulDiv(int64(sub.End), num, den, RoundNearest),
+				)
+			}
+
+			if !yield(sub, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  FrameRate
+	}{
+		{"25", FrameRate{25, 1}},
+		{"25.000", FrameRate{25, 1}},
+		{"30", FrameRate{30, 1}},
+		{"50", FrameRate{50, 1}},
+		{"60", FrameRate{60, 1}},
+		{"23.976", FrameRate{24000, 1001}},
+		{"23.98", FrameRate{24000, 1001}},
+		{"29.97", FrameRate{30000, 1001}},
+		{"59.94", FrameRate{60000, 1001}},
+		{"12.5", FrameRate{25, 2}},
+		{"24000/1001", FrameRate{24000, 1001}},
+		{"50/2", FrameRate{25, 1}},
+		{" 25 ", FrameRate{25, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFrameRate(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFrameRate_Errors(t *testing.T) {
+	for _, input := range []string{"", "abc", "0", "-25", "25/0", "1/x", "NaN", "5000"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseFrameRate(input)
+			if !errors.Is(err, ErrInvalidFrameRate) {
+				t.Errorf("expected ErrInvalidFrameRate, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFrameRate_String(t *testing.T) {
+	tests := []struct {
+		rate FrameRate
+		want string
+	}{
+		{FrameRate{25, 1}, "25.000"},
+		{FrameRate{24000, 1001}, "23.976"},
+		{FrameRate{30000, 1001}, "29.970"},
+		{FrameRate{}, "0.000"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rate.String(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestFrameRate_Conversions(t *testing.T) {
+	tests := []struct {
+		rate     FrameRate
+		frame    int64
+		duration time.Duration
+	}{
+		{FrameRate{25, 1}, 25, time.Second},
+		{FrameRate{25, 1}, 1, 40 * time.Millisecond},
+		{FrameRate{30000, 1001}, 30, 1001 * time.Millisecond},
+		{FrameRate{60, 1}, 90000, 25 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rate.frameToDuration(tt.frame); got != tt.duration {
+			t.Errorf("%v: frame %d: expected %v, got %v", tt.rate, tt.frame, tt.duration, got)
+		}
+
+		if got := tt.rate.durationToFrame(tt.duration); got != tt.frame {
+			t.Errorf("%v: duration %v: expected frame %d, got %d", tt.rate, tt.duration, tt.frame, got)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		x, num, den int64
+		want        int64
+	}{
+		{10, 1, 3, 3},
+		{20, 1, 3, 7},
+		{5, 1, 2, 3},
+		{-5, 1, 2, -3},
+		{5, 1, -2, -3},
+		{math.MaxInt64, 3, 3, math.MaxInt64},
+		{math.MaxInt64, 2, 1, math.MaxInt64},
+		{math.MinInt64, 2, 1, math.MinInt64},
+	}
+
+	for _, tt := range tests {
+		if got := mulDiv(tt.x, tt.num, tt.den, RoundNearest); got != tt.want {
+			t.Errorf("%d*%d/%d: expected %d, got %d", tt.x, tt.num, tt.den, tt.want, got)
+		}
+	}
+}
+
+func TestConvertFrameRate(t *testing.T) {
+	film := FrameRate{24000, 1001}
+	pal := FrameRate{25, 1}
+
+	tests := []struct {
+		name     string
+		from, to FrameRate
+		in, want time.Duration
+	}{
+		{"PAL speedup", film, pal, 1001 * time.Second, 960 * time.Second},
+		{"PAL slowdown", pal, film, 960 * time.Second, 1001 * time.Second},
+		{"NTSC pulldown", FrameRate{24, 1}, film, 24 * time.Second, 24024 * time.Millisecond},
+		{"same rate", pal, pal, 1234 * time.Millisecond, 1234 * time.Millisecond},
+		{"default source", FrameRate{}, pal, 1001 * time.Second, 960 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subs := func(yield func(Subtitle, error) bool) {
+				yield(Subtitle{Start: tt.in, End: 2 * tt.in}, nil)
+			}
+
+			for sub, err := range ConvertFrameRate(subs, tt.from, tt.to) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if sub.Start != tt.want || sub.End != 2*tt.want {
+					t.Errorf("expected %v, got %+v", tt.want, sub)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertFrameRate_KeepsTxtFrames(t *testing.T) {
+	// Frames read at the source rate are written unchanged at the target
+	var input strings.Builder
+	for frame := int64(0); frame < 5*60*60*24; frame += 7 {
+		fmt.Fprintf(&input, "{%d}{%d}Text\n", frame, frame+5)
+	}
+
+	film := FrameRate{24000, 1001}
+	pal := FrameRate{25, 1}
+
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(&buf, TxtFormat, PrinterConfig{FrameRate: pal})
+	subs := NewSubtitlesIterWithConfig(
+		strings.NewReader(input.String()),
+		TxtFormat,
+		ReaderConfig{FrameRate: film},
+	)
+
+	for sub, err := range ConvertFrameRate(subs, film, pal) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if buf.String() != input.String() {
+		t.Error("expected frame numbers to survive the conversion")
+	}
+}
+
+func TestFrameRate_DurationAndFrame(t *testing.T) {
+	film := FrameRate{24000, 1001}
+
+	if got := film.Duration(1); got != 41708333*time.Nanosecond {
+		t.Errorf("expected 41.708333ms, got %v", got)
+	}
+
+	if got := film.Duration(24000); got != 1001*time.Second {
+		t.Errorf("expected 1001s, got %v", got)
+	}
+
+	tests := []struct {
+		d        time.Duration
+		rounding Rounding
+		want     int64
+	}{
+		{41 * time.Millisecond, RoundNearest, 1},
+		{41 * time.Millisecond, RoundDown, 0},
+		{41 * time.Millisecond, RoundUp, 1},
+		{20 * time.Millisecond, RoundNearest, 0},
+		{film.Duration(5), RoundDown, 5},
+		{film.Duration(5), RoundNearest, 5},
+		{-41 * time.Millisecond, RoundDown, -1},
+		{-41 * time.Millisecond, RoundUp, 0},
+	}
+
+	for _, tt := range tests {
+		if got := film.Frame(tt.d, tt.rounding); got != tt.want {
+			t.Errorf("%v with rounding %d: expected frame %d, got %d", tt.d, tt.rounding, tt.want, got)
+		}
+	}
+}
+
+func TestFrameRate_MillisecondRoundTrip(t *testing.T) {
+	rates := []FrameRate{
+		{24000, 1001}, {24, 1}, {25, 1}, {30000, 1001},
+		{30, 1}, {50, 1}, {60000, 1001}, {60, 1},
+	}
+
+	for _, rate := range rates {
+		// Every frame of the first 4 hours survives millisecond timestamps
+		last := rate.Frame(4*time.Hour, RoundUp)
+
+		for frame := range last {
+			d := rate.frameToDuration(frame)
+			if d%time.Millisecond != 0 {
+				t.Fatalf("%v: frame %d: %v is not whole milliseconds", rate, frame, d)
+			}
+
+			if got := rate.durationToFrame(d); got != frame {
+				t.Fatalf("%v: frame %d: %v restores frame %d", rate, frame, d, got)
+			}
+		}
+	}
+}
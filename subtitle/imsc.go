@@ -0,0 +1,359 @@
+package subtitle
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IMSC1 documents are TTML documents restricted by the Text Profile, e.g.
+// to regions within the video and to at most 4 regions on screen at once.
+const (
+	imscProfile            = "http://www.w3.org/ns/ttml/profile/imsc1/text"
+	imscParameterNamespace = "http://www.w3.org/ns/ttml/profile/imsc1#parameter"
+	imscStylingNamespace   = "http://www.w3.org/ns/ttml/profile/imsc1#styling"
+	// imscMaxRegions is the most regions presented at the same time
+	imscMaxRegions = 4
+	// Root container size of pixel lengths, e.g. font sizes, when the
+	// document has no script resolution
+	imscWidth  = 1920
+	imscHeight = 1080
+	// imscTolerance absorbs the rounding of percentages
+	imscTolerance = 0.01
+	// imscLength is a non-negative length in pixels, percent or cells
+	imscLength = `\d+(?:\.\d+)?(?:px|%|c)`
+	// imscFamily is a quoted font family name or unquoted identifiers
+	imscFamily = `(?:"[^"]+"|'[^']+'|` +
+		imscIdent + `(?:\s+` + imscIdent + `)*)`
+	imscIdent = `-?[_\pL][-_\pL\pN]*`
+)
+
+var ErrNotConformant = errors.New("not conformant to the IMSC1 text profile")
+
+var (
+	// imscRegion is written for documents without regions, centred above
+	// the bottom of the video
+	imscRegion = Region{
+		ID: "bottom",
+		Settings: "width:80% lines:2 " +
+			"regionanchor:50%,100% viewportanchor:50%,90%",
+	}
+	// imscValuePatterns are the syntaxes of the attribute values taken from
+	// the cues and regions, with lengths in the units of the profile
+	imscValuePatterns = map[string]*regexp.Regexp{
+		"tts:color": regexp.MustCompile(
+			`^#[0-9A-Fa-f]{6}(?:[0-9A-Fa-f]{2})?$`,
+		),
+		"tts:fontFamily": regexp.MustCompile(
+			`^` + imscFamily + `(?:,\s*` + imscFamily + `)*$`,
+		),
+		"tts:fontSize": regexp.MustCompile(`^` + imscLength + `$`),
+		"tts:origin": regexp.MustCompile(
+			`^` + imscLength + ` ` + imscLength + `$`,
+		),
+		"tts:extent": regexp.MustCompile(
+			`^` + imscLength + ` ` + imscLength + `$`,
+		),
+	}
+)
+
+// imscBox is the area of a region in percent of the video.
+type imscBox struct {
+	x, y, width, height float64
+}
+
+func (b imscBox) overlaps(other imscBox) bool {
+	return b.x+imscTolerance < other.x+other.width &&
+		other.x+imscTolerance < b.x+b.width &&
+		b.y+imscTolerance < other.y+other.height &&
+		other.y+imscTolerance < b.y+b.height
+}
+
+// imscCue is a cue presented in a region until its end.
+type imscCue struct {
+	end    time.Duration
+	region string
+}
+
+// imscChecker verifies the Text Profile constraints of the document before
+// it is written.
+type imscChecker struct {
+	regions map[string]imscBox
+	// active are the cues presented at the start of the last one
+	active []imscCue
+}
+
+// checkImscValues verifies the syntax of the attribute values taken from
+// the cues and regions, e.g. font sizes, which have to be non-negative
+// lengths.
+func checkImscValues(attrs []ttmlAttribute) error {
+	for _, attr := range attrs {
+		pattern, ok := imscValuePatterns[attr.name]
+		if ok && !pattern.MatchString(attr.value) {
+			return fmt.Errorf(
+				"%w: invalid %s %q",
+				ErrNotConformant,
+				attr.name,
+				attr.value,
+			)
+		}
+	}
+
+	return nil
+}
+
+// checkImscCharacters verifies that the text is UTF-8, the encoding the
+// profile requires, made of assigned characters a processor can present:
+// no control characters other than tabs and line breaks, no surrogates or
+// non-characters, and no private-use or unassigned code points. The
+// per-language character sets the profile recommends are not enforced.
+func checkImscCharacters(text string) error {
+	if !utf8.ValidString(text) {
+		return fmt.Errorf("%w: text is not valid UTF-8", ErrNotConformant)
+	}
+
+	for _, r := range text {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Co, r):
+		case r >= 0xD800 && r <= 0xDFFF, r&0xFFFE == 0xFFFE,
+			r >= 0xFDD0 && r <= 0xFDEF:
+		// Unassigned code points have no general category
+		case !unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P,
+			unicode.S, unicode.Z, unicode.Cf):
+		default:
+			continue
+		}
+
+		return fmt.Errorf(
+			"%w: character %U is not allowed",
+			ErrNotConformant,
+			r,
+		)
+	}
+
+	return nil
+}
+
+// newImscChecker verifies the regions, which need valid and unique IDs and
+// have to lie within the video.
+func newImscChecker(regions []Region) (*imscChecker, error) {
+	checker := &imscChecker{regions: make(map[string]imscBox)}
+
+	for _, region := range regions {
+		if !isNCName(region.ID) {
+			return nil, fmt.Errorf(
+				"%w: region ID %q is not a valid xml:id",
+				ErrNotConformant,
+				region.ID,
+			)
+		}
+
+		if _, found := checker.regions[region.ID]; found {
+			return nil, fmt.Errorf(
+				"%w: region %q is defined twice",
+				ErrNotConformant,
+				region.ID,
+			)
+		}
+
+		var box imscBox
+
+		box.x, box.y, box.width, box.height = ttmlRegionBox(region)
+		if box.x < -imscTolerance || box.y < -imscTolerance ||
+			box.x+box.width > 100+imscTolerance ||
+			box.y+box.height > 100+imscTolerance {
+			return nil, fmt.Errorf(
+				"%w: region %q is outside the video",
+				ErrNotConformant,
+				region.ID,
+			)
+		}
+
+		if err := checkImscValues(ttmlRegionAttrs(region)); err != nil {
+			return nil, err
+		}
+
+		checker.regions[region.ID] = box
+	}
+
+	return checker, nil
+}
+
+// check verifies a cue written with the attributes, given in start order:
+// its attributes and text, and the regions presented with it, which may
+// not overlap nor exceed imscMaxRegions.
+func (c *imscChecker) check(sub Subtitle, attrs []ttmlAttribute) error {
+	err := c.checkCue(sub, attrs)
+	if err != nil {
+		return fmt.Errorf("cue at %s: %w", ttmlClock(sub.Start), err)
+	}
+
+	return nil
+}
+
+func (c *imscChecker) checkCue(sub Subtitle, attrs []ttmlAttribute) error {
+	for _, span := range flattenRuby(sub.spans()) {
+		if err := checkImscValues(ttmlSpanAttrs(span)); err != nil {
+			return err
+		}
+
+		if err := checkImscCharacters(span.Text); err != nil {
+			return err
+		}
+	}
+
+	var region string
+
+	for _, attr := range attrs {
+		if attr.name == "region" {
+			region = attr.value
+		}
+	}
+
+	if _, found := c.regions[region]; !found {
+		return fmt.Errorf("%w: unknown region %q", ErrNotConformant, region)
+	}
+
+	c.active = slices.DeleteFunc(c.active, func(cue imscCue) bool {
+		return cue.end <= sub.Start
+	})
+
+	var presented []string
+
+	for _, cue := range c.active {
+		if !slices.Contains(presented, cue.region) {
+			presented = append(presented, cue.region)
+		}
+	}
+
+	if !slices.Contains(presented, region) {
+		if len(presented) == imscMaxRegions {
+			return fmt.Errorf(
+				"%w: more than %d regions presented at once",
+				ErrNotConformant,
+				imscMaxRegions,
+			)
+		}
+
+		for _, other := range presented {
+			if c.regions[region].overlaps(c.regions[other]) {
+				return fmt.Errorf(
+					"%w: region %q overlaps region %q presented at once",
+					ErrNotConformant,
+					region,
+					other,
+				)
+			}
+		}
+	}
+
+	if sub.End > sub.Start {
+		c.active = append(c.active, imscCue{end: sub.End, region: region})
+	}
+
+	return nil
+}
+
+// imscRootAttrs returns the attributes of the <tt> element declaring the
+// profile, and the root container for pixel lengths.
+func imscRootAttrs(doc *Document) []ttmlAttribute {
+	width, height := imscWidth, imscHeight
+	if doc.PlayResX > 0 && doc.PlayResY > 0 {
+		width, height = doc.PlayResX, doc.PlayResY
+	}
+
+	return []ttmlAttribute{
+		{"xmlns:ittp", imscParameterNamespace},
+		{"xmlns:itts", imscStylingNamespace},
+		{"ttp:profile", imscProfile},
+		{
+			"tts:extent",
+			strconv.Itoa(width) + "px " + strconv.Itoa(height) + "px",
+		},
+	}
+}
+
+// writeImscDocument checks the whole document against the profile before
+// writing any of it, with the cues sorted by start time. Cues without a
+// region are put in the first one, imscRegion when the document has none.
+func writeImscDocument(
+	w io.Writer,
+	config PrinterConfig,
+	cues []Subtitle,
+) error {
+	doc := config.document()
+
+	// The profile requires a non-empty xml:lang on <tt>
+	lang := config.language()
+	if lang == "" {
+		return fmt.Errorf("%w: the document has no language", ErrNotConformant)
+	}
+
+	regions := doc.Regions
+	if len(regions) == 0 {
+		regions = []Region{imscRegion}
+	}
+
+	checker, err := newImscChecker(regions)
+	if err != nil {
+		return err
+	}
+
+	// The checker follows the cues presented at once in start order, which
+	// neither decoders nor transforms guarantee
+	slices.SortStableFunc(cues, func(a, b Subtitle) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+
+	attrs := make([][]ttmlAttribute, len(cues))
+	for i, sub := range cues {
+		region := cmp.Or(ttmlRegionSetting(sub.Vtt.Settings), regions[0].ID)
+
+		attrs[i] = ttmlCueAttrs(sub, region)
+		if err := checker.check(sub, attrs[i]); err != nil {
+			return err
+		}
+	}
+
+	err = writeTtmlHeader(w, doc, lang, imscRootAttrs(doc), regions)
+	if err != nil {
+		return err
+	}
+
+	for i, sub := range cues {
+		if err := writeTtmlCue(w, attrs[i], sub); err != nil {
+			return err
+		}
+	}
+
+	return writeTtmlFooter(w)
+}
+
+// newImscPrinter returns a TTML printer of the IMSC1 Text Profile. The
+// document needs a language, the configured or the Document one. Cues are
+// kept until Close, which writes the document only when all of it conforms
+// to the profile, so that a failed check leaves no partial output. Abort
+// drops the kept cues, leaving no output for a stream that failed to read.
+func newImscPrinter(writer io.Writer, config PrinterConfig) Printer {
+	var cues []Subtitle
+
+	return &printer{
+		writer: writer,
+		write: func(_ io.Writer, sub Subtitle) error {
+			cues = append(cues, sub)
+			return nil
+		},
+		footer: func(w io.Writer) error {
+			return writeImscDocument(w, config, cues)
+		},
+	}
+}
@@ -0,0 +1,261 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSubtitlePrinter_ImscFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	// The configured language is written for a document without one
+	doc := &Document{PlayResX: 1280, PlayResY: 720}
+	printer := NewSubtitlePrinterWithConfig(&buf, ImscFormat, PrinterConfig{Document: doc, Language: "en"})
+
+	subs := []Subtitle{
+		{Vtt: VttCue{ID: "1"}, Start: time.Second, End: 2 * time.Second, Text: "Hi <you>", Spans: &StyledText{{Text: "Hi "}, {Text: "<you>", Size: 40}}},
+		{Vtt: VttCue{ID: "c2"}, Start: 2 * time.Second, End: 3 * time.Second, Text: "Plain"},
+	}
+	for _, sub := range subs {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" xmlns:ittp="http://www.w3.org/ns/ttml/profile/imsc1#parameter" xmlns:itts="http://www.w3.org/ns/ttml/profile/imsc1#styling" ttp:profile="http://www.w3.org/ns/ttml/profile/imsc1/text" tts:extent="1280px 720px" ttp:timeBase="media" xml:lang="en">
+  <head>
+    <layout>
+      <region xml:id="bottom" tts:origin="10% 79.34%" tts:extent="80% 10.66%"/>
+    </layout>
+  </head>
+  <body>
+    <div>
+      <p begin="00:00:01.000" end="00:00:02.000" region="bottom">Hi <span tts:fontSize="40px">&lt;you&gt;</span></p>
+      <p xml:id="c2" begin="00:00:02.000" end="00:00:03.000" region="bottom">Plain</p>
+    </div>
+  </body>
+</tt>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// The output is read back as TTML
+	format, _, err := DetectFormat("out.xml", strings.NewReader(want))
+	if err != nil || format != TtmlFormat {
+		t.Errorf("expected %v, got %v (%v)", TtmlFormat, format, err)
+	}
+}
+
+func TestNewSubtitlePrinter_ImscFormat_Abort(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(&buf, ImscFormat, PrinterConfig{Language: "en"})
+
+	sub := Subtitle{Start: time.Second, End: 2 * time.Second, Text: "First"}
+	if err := printer.Write(sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A failed read aborts the stream before Close
+	printer.Abort()
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no output for an aborted stream, got %q", buf.String())
+	}
+}
+
+func TestNewSubtitlePrinter_ImscFormat_Errors(t *testing.T) {
+	top := Region{ID: "top", Settings: "width:80% lines:2 regionanchor:50%,0% viewportanchor:50%,10%"}
+	bottom := Region{ID: "bottom", Settings: "width:80% lines:2 regionanchor:50%,100% viewportanchor:50%,90%"}
+	corners := []Region{
+		{ID: "a", Settings: "width:20% lines:1 regionanchor:0%,0% viewportanchor:0%,0%"},
+		{ID: "b", Settings: "width:20% lines:1 regionanchor:0%,0% viewportanchor:25%,0%"},
+		{ID: "c", Settings: "width:20% lines:1 regionanchor:0%,0% viewportanchor:50%,0%"},
+		{ID: "d", Settings: "width:20% lines:1 regionanchor:0%,0% viewportanchor:75%,0%"},
+		{ID: "e", Settings: "width:20% lines:1 regionanchor:0%,0% viewportanchor:0%,50%"},
+	}
+
+	tests := []struct {
+		name    string
+		regions []Region
+		subs    []Subtitle
+		wantErr string
+	}{
+		{
+			name:    "region outside the video",
+			regions: []Region{{ID: "low", Settings: "width:80% lines:2 regionanchor:0%,0% viewportanchor:10%,95%"}},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi"}},
+			wantErr: `region "low" is outside the video`,
+		},
+		{
+			name:    "invalid region ID",
+			regions: []Region{{ID: "1", Settings: bottom.Settings}},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi"}},
+			wantErr: `region ID "1" is not a valid xml:id`,
+		},
+		{
+			name:    "unknown region",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi", Vtt: VttCue{Settings: "region:top"}}},
+			wantErr: `cue at 00:00:00.000: not conformant to the IMSC1 text profile: unknown region "top"`,
+		},
+		{
+			name:    "control character after a valid cue",
+			regions: []Region{bottom},
+			subs: []Subtitle{
+				{Start: 0, End: time.Second, Text: "Hi"},
+				{Start: time.Second, End: 2 * time.Second, Text: "Hi\x07"},
+			},
+			wantErr: "cue at 00:00:01.000",
+		},
+		{
+			name:    "control character",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi\x07"}},
+			wantErr: "character U+0007 is not allowed",
+		},
+		{
+			name:    "private-use character",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi\uE000"}},
+			wantErr: "character U+E000 is not allowed",
+		},
+		{
+			name:    "unassigned character",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi\u0378"}},
+			wantErr: "character U+0378 is not allowed",
+		},
+		{
+			name:    "non-character",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi\U0001FFFF"}},
+			wantErr: "character U+1FFFF is not allowed",
+		},
+		{
+			name:    "negative font size",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi", Spans: &StyledText{{Text: "Hi", Size: -20}}}},
+			wantErr: `invalid tts:fontSize "-20px"`,
+		},
+		{
+			name:    "unquoted font family starting with a digit",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi", Spans: &StyledText{{Text: "Hi", Font: "8bit Sans"}}}},
+			wantErr: `invalid tts:fontFamily "8bit Sans"`,
+		},
+		{
+			name:    "font families",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "Hi", Spans: &StyledText{{Text: "Hi", Font: `Open Sans, "8bit", sansSerif`}}}},
+		},
+		{
+			name:    "assigned characters",
+			regions: []Region{bottom},
+			subs:    []Subtitle{{Start: 0, End: time.Second, Text: "안녕\t«¡olé!»\u200F ❤"}},
+		},
+		{
+			name:    "overlapping regions",
+			regions: []Region{bottom, {ID: "wide", Settings: "width:100% lines:3 regionanchor:0%,100% viewportanchor:0%,100%"}},
+			subs: []Subtitle{
+				{Start: 0, End: 2 * time.Second, Text: "Hi", Vtt: VttCue{Settings: "region:bottom"}},
+				{Start: time.Second, End: 2 * time.Second, Text: "There", Vtt: VttCue{Settings: "region:wide"}},
+			},
+			wantErr: `region "wide" overlaps region "bottom"`,
+		},
+		{
+			name:    "overlapping regions out of order",
+			regions: []Region{top, bottom, {ID: "wide", Settings: "width:100% lines:3 regionanchor:0%,100% viewportanchor:0%,100%"}},
+			subs: []Subtitle{
+				{Start: 0, End: 2 * time.Second, Text: "A", Vtt: VttCue{Settings: "region:bottom"}},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "B", Vtt: VttCue{Settings: "region:top"}},
+				{Start: time.Second, End: 2 * time.Second, Text: "C", Vtt: VttCue{Settings: "region:wide"}},
+			},
+			wantErr: `region "wide" overlaps region "bottom"`,
+		},
+		{
+			name:    "too many regions",
+			regions: corners,
+			subs: []Subtitle{
+				{Start: 0, End: 5 * time.Second, Text: "A", Vtt: VttCue{Settings: "region:a"}},
+				{Start: time.Second, End: 5 * time.Second, Text: "B", Vtt: VttCue{Settings: "region:b"}},
+				{Start: 2 * time.Second, End: 5 * time.Second, Text: "C", Vtt: VttCue{Settings: "region:c"}},
+				{Start: 3 * time.Second, End: 5 * time.Second, Text: "D", Vtt: VttCue{Settings: "region:d"}},
+				{Start: 4 * time.Second, End: 5 * time.Second, Text: "E", Vtt: VttCue{Settings: "region:e"}},
+			},
+			wantErr: "more than 4 regions presented at once",
+		},
+		{
+			name:    "regions presented one after another",
+			regions: []Region{top, bottom, {ID: "wide", Settings: "width:100% lines:3 regionanchor:0%,100% viewportanchor:0%,100%"}},
+			subs: []Subtitle{
+				{Start: 0, End: time.Second, Text: "A", Vtt: VttCue{Settings: "region:top"}},
+				{Start: 0, End: time.Second, Text: "B", Vtt: VttCue{Settings: "region:bottom"}},
+				{Start: time.Second, End: 2 * time.Second, Text: "C", Vtt: VttCue{Settings: "region:wide"}},
+			},
+		},
+		{
+			name:    "regions presented one after another out of order",
+			regions: []Region{top, bottom, {ID: "wide", Settings: "width:100% lines:3 regionanchor:0%,100% viewportanchor:0%,100%"}},
+			subs: []Subtitle{
+				{Start: time.Second, End: 2 * time.Second, Text: "C", Vtt: VttCue{Settings: "region:wide"}},
+				{Start: 0, End: time.Second, Text: "A", Vtt: VttCue{Settings: "region:top"}},
+				{Start: 0, End: time.Second, Text: "B", Vtt: VttCue{Settings: "region:bottom"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			doc := &Document{Language: "en", Regions: tt.regions}
+			printer := NewSubtitlePrinterWithConfig(&buf, ImscFormat, PrinterConfig{Document: doc})
+
+			var err error
+			for _, sub := range tt.subs {
+				if err = printer.Write(sub); err != nil {
+					break
+				}
+			}
+
+			if err == nil {
+				err = printer.Close()
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, ErrNotConformant) {
+				t.Fatalf("expected ErrNotConformant, got %v", err)
+			}
+
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+
+			// Nothing is written when the document does not conform
+			if buf.Len() != 0 {
+				t.Errorf("expected no output, got %q", buf.String())
+			}
+		})
+	}
+}
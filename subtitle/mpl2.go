@@ -0,0 +1,90 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"regexp"
+	"strings"
+)
+
+// MPL2 times cues in deciseconds, e.g. [123][456]text|/italic
+var (
+	mpl2 = txtDialect{
+		name:  "mpl2",
+		open:  "[",
+		close: "]",
+		unit:  "time",
+		rate:  FrameRate{Num: 10, Den: 1},
+		// MPL2 has no control codes, only the / italic prefix
+		parseText: parseSlashText,
+	}
+	mpl2LinePattern = regexp.MustCompile(`^\[\d+\]\[\d*\]`)
+)
+
+func newMpl2SubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return mpl2.subtitlesIter(next, stop, config)
+}
+
+// parseSlashText parses the |-separated lines of an MPL2 or TMPlayer cue
+// into spans, with lines starting with / in italics.
+func parseSlashText(text string) []Span {
+	lines := strings.Split(text, "|")
+	spans := make([]Span, 0, 2*len(lines))
+
+	for i, line := range lines {
+		if i > 0 {
+			spans = append(spans, Span{Text: "\n"})
+		}
+
+		line, italic := strings.CutPrefix(line, "/")
+		spans = append(spans, Span{Text: line, Italic: italic})
+	}
+
+	return mergeSpans(spans)
+}
+
+// formatSlashText renders the text of the cue as |-separated lines, with the
+// / prefix on lines that are wholly italic, as in MPL2 and TMPlayer. Other
+// styling is dropped.
+func formatSlashText(sub Subtitle) string {
+	if sub.Spans == nil {
+		return strings.ReplaceAll(sub.Text, "\n", "|")
+	}
+
+	lines := spanLines(flattenRuby(*sub.Spans))
+	texts := make([]string, len(lines))
+
+	for i, line := range lines {
+		texts[i] = spansText(line)
+
+		if len(line) > 0 && commonStyle(line).Italic {
+			texts[i] = "/" + texts[i]
+		}
+	}
+
+	return strings.Join(texts, "|")
+}
+
+func writeMpl2Subtitle(w io.Writer, sub Subtitle) error {
+	_, err := fmt.Fprintf(
+		w,
+		"[%d][%d]%s\n",
+		mpl2.rate.durationToFrame(sub.Start),
+		mpl2.rate.durationToFrame(sub.End),
+		formatSlashText(sub),
+	)
+
+	return err
+}
+
+func newMpl2Printer(writer io.Writer, _ PrinterConfig) Printer {
+	return &printer{
+		writer: writer,
+		write:  writeMpl2Subtitle,
+	}
+}
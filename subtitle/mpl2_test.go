@@ -0,0 +1,129 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSubtitlesIter_Mpl2Format(t *testing.T) {
+	input := "[10][25]First line|/Italic line\n" +
+		"\n" +
+		"[ 30 ][45]{y:b}Bold\n" +
+		"[50][]Open ended\n" +
+		"[60][70]Last\n"
+
+	want := []Subtitle{
+		{
+			Start: time.Second,
+			End:   2500 * time.Millisecond,
+			Text:  "First line\nItalic line",
+			Spans: &StyledText{{Text: "First line\n"}, {Text: "Italic line", Italic: true}},
+		},
+		// MicroDVD control codes are text in MPL2
+		{Start: 3 * time.Second, End: 4500 * time.Millisecond, Text: "{y:b}Bold"},
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "Open ended"},
+		{Start: 6 * time.Second, End: 7 * time.Second, Text: "Last"},
+	}
+
+	var got []Subtitle
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), Mpl2Format) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got = append(got, sub)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestNewSubtitlesIter_Mpl2Format_Errors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr error
+		wantMsg string
+	}{
+		{"[10][20]Text\n{10}{20}Text\n", ErrMissingBrace, "2:1: missing brace: expected '['"},
+		{"[10][x]Text\n", ErrInvalidFrame, `invalid end time "x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var err error
+			for _, err = range NewSubtitlesIter(strings.NewReader(tt.input), Mpl2Format) {
+				if err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("expected %v with %q, got %v", tt.wantErr, tt.wantMsg, err)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlePrinter_Mpl2Format(t *testing.T) {
+	subs := []Subtitle{
+		{
+			Start: 1040 * time.Millisecond,
+			End:   2960 * time.Millisecond,
+			Text:  "Plain\nItalic",
+			Spans: &StyledText{{Text: "Plain\n"}, {Text: "Italic", Italic: true, Color: "red"}},
+		},
+		{
+			Start: 3 * time.Second,
+			End:   4 * time.Second,
+			Text:  "Half italic",
+			Spans: &StyledText{{Text: "Half "}, {Text: "italic", Italic: true}},
+		},
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "Two\nlines"},
+	}
+
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinter(&buf, Mpl2Format)
+	for _, sub := range subs {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "[10][30]Plain|/Italic\n[30][40]Half italic\n[50][60]Two|lines\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestNewSubtitlesIter_Mpl2ToSrt(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinter(&buf, SrtFormat)
+	input := "[12][34]/First|Second\n"
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), Mpl2Format) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	want := "1\n00:00:01,200 --> 00:00:03,400\n<i>First</i>\nSecond\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
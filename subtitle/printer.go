@@ -0,0 +1,103 @@
+package subtitle
+
+import (
+	"errors"
+	"io"
+)
+
+// Printer writes the subtitles of one format to a stream. Formats with a
+// document header write it before the first cue, or on Close when there are
+// no cues, and formats with a footer write it on Close.
+type Printer interface {
+	// Write writes a single cue
+	Write(sub Subtitle) error
+	// Flush passes buffered output on, when the underlying writer has a
+	// Flush method, e.g. a bufio.Writer
+	Flush() error
+	// Close finishes the document and flushes it, leaving the underlying
+	// writer open. Closing again does nothing.
+	Close() error
+	// Abort stops the document without finishing it, after the cues failed
+	// to be read or written. Neither the footer nor buffered cues are
+	// written, and Close does nothing afterwards.
+	Abort()
+}
+
+var ErrPrinterClosed = errors.New("printer is closed")
+
+// printer implements Printer for the built-in formats.
+type printer struct {
+	writer io.Writer
+	// header is given the first cue, or nil when closing an empty document
+	header func(w io.Writer, first *Subtitle) error
+	write  func(w io.Writer, sub Subtitle) error
+	footer func(w io.Writer) error
+	// events keeps cues that are not shown, e.g. ASS comments, which the
+	// other formats leave out
+	events bool
+
+	started bool
+	closed  bool
+}
+
+func (p *printer) start(first *Subtitle) error {
+	if p.started {
+		return nil
+	}
+
+	p.started = true
+
+	if p.header == nil {
+		return nil
+	}
+
+	return p.header(p.writer, first)
+}
+
+func (p *printer) Write(sub Subtitle) error {
+	if p.closed {
+		return ErrPrinterClosed
+	}
+
+	if sub.hidden() && !p.events {
+		return nil
+	}
+
+	if err := p.start(&sub); err != nil {
+		return err
+	}
+
+	return p.write(p.writer, sub)
+}
+
+func (p *printer) Flush() error {
+	if flusher, ok := p.writer.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+func (p *printer) Close() error {
+	if p.closed {
+		return nil
+	}
+
+	p.closed = true
+
+	if err := p.start(nil); err != nil {
+		return err
+	}
+
+	if p.footer != nil {
+		if err := p.footer(p.writer); err != nil {
+			return err
+		}
+	}
+
+	return p.Flush()
+}
+
+func (p *printer) Abort() {
+	p.closed = true
+}
@@ -0,0 +1,152 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrinter_CloseEmptyDocument(t *testing.T) {
+	tests := []struct {
+		name   string
+		format FileFormat
+		config PrinterConfig
+		want   string
+	}{
+		{
+			name:   "srt",
+			format: SrtFormat,
+			want:   "",
+		},
+		{
+			name:   "txt",
+			format: TxtFormat,
+			want:   "",
+		},
+		{
+			name:   "txt with frame rate header",
+			format: TxtFormat,
+			config: PrinterConfig{FrameRateHeader: true},
+			want:   "{1}{1}23.976\n",
+		},
+		{
+			name:   "vtt",
+			format: VttFormat,
+			want:   "WEBVTT\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			printer := NewSubtitlePrinterWithConfig(&buf, tt.format, tt.config)
+			if err := printer.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestPrinter_CloseEmptyAssDocument(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinter(&buf, AssFormat)
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := buf.String()
+	events := "[Events]\nFormat: " + strings.Join(assEventFormat, ", ") + "\n"
+
+	if !strings.HasPrefix(got, "[Script Info]\n") ||
+		!strings.HasSuffix(got, events) {
+		t.Errorf("expected the default header, got:\n%s", got)
+	}
+}
+
+func TestPrinter_WriteAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinter(&buf, VttFormat)
+	sub := Subtitle{Start: time.Second, End: 2 * time.Second, Text: "Text"}
+
+	if err := printer.Write(sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Closing again must not write another header
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error on second close: %v", err)
+	}
+
+	if err := printer.Write(sub); !errors.Is(err, ErrPrinterClosed) {
+		t.Errorf("expected ErrPrinterClosed, got %v", err)
+	}
+
+	expected := "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nText\n\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestPrinter_AbortLeavesFooterOut(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinter(&buf, TtmlFormat)
+	sub := Subtitle{Start: time.Second, End: 2 * time.Second, Text: "Text"}
+
+	if err := printer.Write(sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	printer.Abort()
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error on close after abort: %v", err)
+	}
+
+	if err := printer.Write(sub); !errors.Is(err, ErrPrinterClosed) {
+		t.Errorf("expected ErrPrinterClosed, got %v", err)
+	}
+
+	if got := buf.String(); strings.Contains(got, "</tt>") {
+		t.Errorf("expected no footer after abort, got:\n%s", got)
+	}
+}
+
+func TestPrinter_Flush(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer := bufio.NewWriter(&buf)
+	printer := NewSubtitlePrinter(writer, SrtFormat)
+
+	sub := Subtitle{Start: time.Second, End: 2 * time.Second, Text: "Text"}
+	if err := printer.Write(sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected output to be buffered, got %q", buf.String())
+	}
+
+	if err := printer.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1\n00:00:01,000 --> 00:00:02,000\nText\n\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
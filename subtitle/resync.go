@@ -0,0 +1,321 @@
+package subtitle
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAnchor = errors.New("invalid sync anchor")
+
+// Anchor is a sync point of Resync, mapping a source time to a target time.
+type Anchor struct {
+	// Cue is the 1-based index of the cue whose start is the source time, or
+	// zero when Source is given
+	Cue    int
+	Source Offset
+	Target Offset
+}
+
+func (a Anchor) String() string {
+	if a.Cue > 0 {
+		return fmt.Sprintf("#%d=%v", a.Cue, a.Target)
+	}
+
+	return fmt.Sprintf("%v=%v", a.Source, a.Target)
+}
+
+// ParseAnchor parses "SOURCE=TARGET", where SOURCE is either a cue number or
+// a time, e.g. "812=01:43:10,000" or "00:10:00,000=00:10:02,500". Times take
+// the forms accepted by ParseOffset.
+func ParseAnchor(value string) (Anchor, error) {
+	source, target, found := strings.Cut(value, "=")
+	if !found {
+		// Anchor files may separate the times with blanks
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return Anchor{}, fmt.Errorf(
+				"%w %q: expected SOURCE=TARGET",
+				ErrInvalidAnchor,
+				value,
+			)
+		}
+
+		source, target = fields[0], fields[1]
+	}
+
+	var (
+		anchor Anchor
+		err    error
+	)
+
+	source = strings.TrimSpace(source)
+	negative := func(offset Offset) bool {
+		return offset.Duration < 0 || offset.Frames < 0
+	}
+
+	if cue, errCue := strconv.ParseUint(source, 10, 31); errCue == nil {
+		if cue == 0 {
+			return Anchor{}, fmt.Errorf(
+				"%w %q: cues are numbered from 1",
+				ErrInvalidAnchor,
+				value,
+			)
+		}
+
+		anchor.Cue = int(cue)
+	} else if anchor.Source, err = ParseOffset(source); err != nil ||
+		negative(anchor.Source) {
+		return Anchor{}, fmt.Errorf(
+			"%w %q: invalid source %q",
+			ErrInvalidAnchor,
+			value,
+			source,
+		)
+	}
+
+	if anchor.Target, err = ParseOffset(target); err != nil {
+		return Anchor{}, fmt.Errorf(
+			"%w %q: invalid target %q",
+			ErrInvalidAnchor,
+			value,
+			strings.TrimSpace(target),
+		)
+	}
+
+	return anchor, nil
+}
+
+// ParseAnchors reads one anchor per line in the ParseAnchor syntax, skipping
+// blank lines and # comments.
+func ParseAnchors(reader io.Reader) ([]Anchor, error) {
+	var anchors []Anchor
+
+	scanner := bufio.NewScanner(reader)
+
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		anchor, err := ParseAnchor(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		anchors = append(anchors, anchor)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read anchors: %w", err)
+	}
+
+	return anchors, nil
+}
+
+// timePoint is an anchor with its times resolved.
+type timePoint struct {
+	anchor Anchor
+	source time.Duration
+	target time.Duration
+}
+
+// timeMap is a piecewise-linear mapping through points sorted by source,
+// extended past both ends along the outer segments.
+type timeMap struct {
+	points []timePoint
+}
+
+func newTimeMap(points []timePoint) (timeMap, error) {
+	if len(points) == 0 {
+		return timeMap{}, fmt.Errorf("%w: no anchors given", ErrInvalidAnchor)
+	}
+
+	slices.SortStableFunc(points, func(a, b timePoint) int {
+		return cmp.Compare(a.source, b.source)
+	})
+
+	for i := 1; i < len(points); i++ {
+		prev, next := points[i-1], points[i]
+
+		if next.source == prev.source {
+			return timeMap{}, fmt.Errorf(
+				"%w: %v and %v share the source time",
+				ErrInvalidAnchor,
+				prev.anchor,
+				next.anchor,
+			)
+		}
+
+		if next.target < prev.target {
+			return timeMap{}, fmt.Errorf(
+				"%w: %v and %v reverse the cue order",
+				ErrInvalidAnchor,
+				prev.anchor,
+				next.anchor,
+			)
+		}
+	}
+
+	return timeMap{points: points}, nil
+}
+
+// apply maps t, rounding to milliseconds and clamping at zero.
+func (m timeMap) apply(t time.Duration) time.Duration {
+	// A single anchor is a constant shift
+	if len(m.points) == 1 {
+		return max(t+m.points[0].target-m.points[0].source, 0)
+	}
+
+	// Find the segment containing t, or the outer one
+	i, _ := slices.BinarySearchFunc(
+		m.points,
+		t,
+		func(p timePoint, t time.Duration) int {
+			return cmp.Compare(p.source, t)
+		},
+	)
+	i = min(max(i, 1), len(m.points)-1)
+
+	from, to := m.points[i-1], m.points[i]
+	scale := float64(to.target-from.target) / float64(to.source-from.source)
+	mapped := float64(from.target) + float64(t-from.source)*scale
+
+	if mapped <= 0 {
+		return 0
+	}
+
+	if mapped >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(mapped).Round(time.Millisecond)
+}
+
+// Resync maps cue times through the piecewise-linear function given by the
+// anchors, which fixes drift between two cuts of a film or frame rate
+// mismatches. Cues before the first or after the last anchor follow the
+// nearest segment, and a single anchor shifts all cues. Frames are counted
+// at rate. Cues are held back until the cues named by anchors are read.
+// Errors of subs are passed on.
+func Resync(
+	subs iter.Seq2[Subtitle, error],
+	anchors []Anchor,
+	rate FrameRate,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		// The last cue an anchor refers to
+		last := 0
+		for _, anchor := range anchors {
+			last = max(last, anchor.Cue)
+		}
+
+		resolve := func(cues []Subtitle) (timeMap, error) {
+			points := make([]timePoint, len(anchors))
+			for i, anchor := range anchors {
+				points[i] = timePoint{
+					anchor: anchor,
+					source: anchor.Source.At(rate),
+					target: anchor.Target.At(rate),
+				}
+
+				if anchor.Cue > 0 {
+					points[i].source = cues[anchor.Cue-1].Start
+				}
+			}
+
+			return newTimeMap(points)
+		}
+
+		type item struct {
+			sub Subtitle
+			err error
+		}
+
+		var (
+			mapping  timeMap
+			resolved = last == 0
+			cues     []Subtitle
+			pending  []item
+		)
+
+		if resolved {
+			var err error
+			if mapping, err = resolve(nil); err != nil {
+				yield(Subtitle{}, err)
+				return
+			}
+		}
+
+		emit := func(sub Subtitle, err error) bool {
+			if err == nil {
+				sub.Start = mapping.apply(sub.Start)
+				sub.End = mapping.apply(sub.End)
+			}
+
+			return yield(sub, err)
+		}
+
+		for sub, err := range subs {
+			if resolved {
+				if !emit(sub, err) {
+					return
+				}
+
+				continue
+			}
+
+			// Anchors count the cues shown
+			pending = append(pending, item{sub, err})
+			if err == nil && !sub.hidden() {
+				cues = append(cues, sub)
+			}
+
+			if len(cues) < last {
+				continue
+			}
+
+			if mapping, err = resolve(cues); err != nil {
+				yield(Subtitle{}, err)
+				return
+			}
+
+			resolved = true
+
+			for _, held := range pending {
+				if !emit(held.sub, held.err) {
+					return
+				}
+			}
+
+			pending = nil
+		}
+
+		if resolved {
+			return
+		}
+
+		// Report the errors that may have cut the input short first
+		for _, held := range pending {
+			if held.err != nil && !yield(held.sub, held.err) {
+				return
+			}
+		}
+
+		yield(Subtitle{}, fmt.Errorf(
+			"%w: anchor refers to cue %d, the input has %d",
+			ErrInvalidAnchor,
+			last,
+			len(cues),
+		))
+	}
+}
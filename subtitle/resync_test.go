@@ -0,0 +1,311 @@
+package subtitle
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAnchor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Anchor
+	}{
+		{"1=00:01:02,300", Anchor{Cue: 1, Target: Offset{Duration: time.Minute + 2300*time.Millisecond}}},
+		{"812 = 01:43:10,000", Anchor{Cue: 812, Target: Offset{Duration: time.Hour + 43*time.Minute + 10*time.Second}}},
+		{"00:10:00,000=00:10:02.5", Anchor{Source: Offset{Duration: 10 * time.Minute}, Target: Offset{Duration: 10*time.Minute + 2500*time.Millisecond}}},
+		{"90s 95s", Anchor{Source: Offset{Duration: 90 * time.Second}, Target: Offset{Duration: 95 * time.Second}}},
+		{"240f=250f", Anchor{Source: Offset{Frames: 240}, Target: Offset{Frames: 250}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAnchor(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseAnchor_Errors(t *testing.T) {
+	for _, value := range []string{"", "1", "0=1s", "-1s=1s", "1=x", "x=1s", "1 2 3"} {
+		t.Run(value, func(t *testing.T) {
+			if _, err := ParseAnchor(value); !errors.Is(err, ErrInvalidAnchor) {
+				t.Errorf("expected ErrInvalidAnchor, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseAnchors(t *testing.T) {
+	input := "# first and last line of dialogue\n" +
+		"1 00:01:02,300\n\n" +
+		"812=01:43:10,000  # end credits\n"
+
+	got, err := ParseAnchors(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Anchor{
+		{Cue: 1, Target: Offset{Duration: time.Minute + 2300*time.Millisecond}},
+		{Cue: 812, Target: Offset{Duration: time.Hour + 43*time.Minute + 10*time.Second}},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	_, err = ParseAnchors(strings.NewReader("1=1s\nbroken\n"))
+	if !errors.Is(err, ErrInvalidAnchor) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected ErrInvalidAnchor on line 2, got %v", err)
+	}
+}
+
+// seqOf yields the subtitles followed by the errors.
+func seqOf(subs []Subtitle, errs ...error) func(func(Subtitle, error) bool) {
+	return func(yield func(Subtitle, error) bool) {
+		for _, sub := range subs {
+			if !yield(sub, nil) {
+				return
+			}
+		}
+
+		for _, err := range errs {
+			if !yield(Subtitle{}, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestResync(t *testing.T) {
+	// Cues of a 25 fps release, every 10 seconds
+	var subs []Subtitle
+	for i := range 5 {
+		start := time.Duration(i*10+1) * time.Second
+		subs = append(subs, Subtitle{Start: start, End: start + 2*time.Second})
+	}
+
+	tests := []struct {
+		name      string
+		anchors   []Anchor
+		rate      FrameRate
+		wantStart []time.Duration
+	}{
+		{
+			name:    "single anchor shifts",
+			anchors: []Anchor{{Cue: 2, Target: Offset{Duration: 12 * time.Second}}},
+			wantStart: []time.Duration{
+				2 * time.Second, 12 * time.Second, 22 * time.Second,
+				32 * time.Second, 42 * time.Second,
+			},
+		},
+		{
+			name:    "frame anchors at the rate",
+			anchors: []Anchor{{Source: Offset{Frames: 275}, Target: Offset{Frames: 300}}},
+			rate:    FrameRate{Num: 25, Den: 1},
+			wantStart: []time.Duration{
+				2 * time.Second, 12 * time.Second, 22 * time.Second,
+				32 * time.Second, 42 * time.Second,
+			},
+		},
+		{
+			name: "two cue anchors scale",
+			anchors: []Anchor{
+				{Cue: 1, Target: Offset{Duration: 1 * time.Second}},
+				{Cue: 5, Target: Offset{Duration: 81 * time.Second}},
+			},
+			wantStart: []time.Duration{
+				1 * time.Second, 21 * time.Second, 41 * time.Second,
+				61 * time.Second, 81 * time.Second,
+			},
+		},
+		{
+			name: "time anchors extrapolate",
+			anchors: []Anchor{
+				{Source: Offset{Duration: 11 * time.Second}, Target: Offset{Duration: 12 * time.Second}},
+				{Source: Offset{Duration: 21 * time.Second}, Target: Offset{Duration: 22 * time.Second}},
+			},
+			wantStart: []time.Duration{
+				2 * time.Second, 12 * time.Second, 22 * time.Second,
+				32 * time.Second, 42 * time.Second,
+			},
+		},
+		{
+			name: "piecewise",
+			anchors: []Anchor{
+				{Cue: 1, Target: Offset{Duration: 1 * time.Second}},
+				{Cue: 3, Target: Offset{Duration: 21 * time.Second}},
+				{Cue: 5, Target: Offset{Duration: 61 * time.Second}},
+			},
+			wantStart: []time.Duration{
+				1 * time.Second, 11 * time.Second, 21 * time.Second,
+				41 * time.Second, 61 * time.Second,
+			},
+		},
+		{
+			name: "before zero is clamped",
+			anchors: []Anchor{
+				{Cue: 2, Target: Offset{}},
+				{Cue: 3, Target: Offset{Duration: 5 * time.Second}},
+			},
+			wantStart: []time.Duration{
+				0, 0, 5 * time.Second, 10 * time.Second, 15 * time.Second,
+			},
+		},
+		{
+			name: "PAL to film speed",
+			anchors: []Anchor{
+				{Source: Offset{}, Target: Offset{}},
+				{Source: Offset{Duration: 24 * time.Second}, Target: Offset{Duration: 25 * time.Second}},
+			},
+			wantStart: []time.Duration{
+				1042 * time.Millisecond, 11458 * time.Millisecond,
+				21875 * time.Millisecond, 32292 * time.Millisecond,
+				42708 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var starts []time.Duration
+
+			for sub, err := range Resync(seqOf(subs), tt.anchors, tt.rate) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if sub.End < sub.Start {
+					t.Errorf("cue ends before it starts: %+v", sub)
+				}
+
+				starts = append(starts, sub.Start)
+			}
+
+			if !slices.Equal(starts, tt.wantStart) {
+				t.Errorf("expected %v, got %v", tt.wantStart, starts)
+			}
+		})
+	}
+}
+
+func TestResync_HiddenEvents(t *testing.T) {
+	subs := []Subtitle{
+		{Start: 0, End: time.Second, Ass: AssEvent{Kind: "Comment"}},
+		{Start: time.Second, End: 2 * time.Second},
+		{Start: 3 * time.Second, End: 4 * time.Second},
+	}
+
+	// Cue anchors count the cues shown, and comments move with them
+	anchors := []Anchor{{Cue: 1, Target: Offset{Duration: 2 * time.Second}}}
+
+	var starts []time.Duration
+
+	for sub, err := range Resync(seqOf(subs), anchors, FrameRate{}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		starts = append(starts, sub.Start)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if !slices.Equal(starts, want) {
+		t.Errorf("expected %v, got %v", want, starts)
+	}
+}
+
+func TestResync_Errors(t *testing.T) {
+	subs := []Subtitle{
+		{Start: time.Second, End: 2 * time.Second},
+		{Start: 3 * time.Second, End: 4 * time.Second},
+	}
+
+	tests := []struct {
+		name    string
+		anchors []Anchor
+		errs    []error
+		wantErr []error
+	}{
+		{
+			name:    "no anchors",
+			wantErr: []error{ErrInvalidAnchor},
+		},
+		{
+			name:    "missing cue",
+			anchors: []Anchor{{Cue: 1}, {Cue: 3, Target: Offset{Duration: time.Second}}},
+			wantErr: []error{ErrInvalidAnchor},
+		},
+		{
+			name:    "missing cue after parse error",
+			anchors: []Anchor{{Cue: 1}, {Cue: 3, Target: Offset{Duration: time.Second}}},
+			errs:    []error{ErrInvalidFrame},
+			wantErr: []error{ErrInvalidFrame, ErrInvalidAnchor},
+		},
+		{
+			name: "same source",
+			anchors: []Anchor{
+				{Cue: 1, Target: Offset{Duration: time.Second}},
+				{Source: Offset{Duration: time.Second}, Target: Offset{Duration: 2 * time.Second}},
+			},
+			wantErr: []error{ErrInvalidAnchor},
+		},
+		{
+			name: "reversed order",
+			anchors: []Anchor{
+				{Cue: 1, Target: Offset{Duration: 5 * time.Second}},
+				{Cue: 2, Target: Offset{Duration: 2 * time.Second}},
+			},
+			wantErr: []error{ErrInvalidAnchor},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []error
+
+			for _, err := range Resync(seqOf(subs, tt.errs...), tt.anchors, FrameRate{}) {
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, errs)
+			}
+
+			for i, err := range errs {
+				if !errors.Is(err, tt.wantErr[i]) {
+					t.Errorf("expected %v, got %v", tt.wantErr[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestResync_EarlyBreak(t *testing.T) {
+	subs := []Subtitle{
+		{Start: time.Second, End: 2 * time.Second},
+		{Start: 3 * time.Second, End: 4 * time.Second},
+		{Start: 5 * time.Second, End: 6 * time.Second},
+	}
+	anchors := []Anchor{{Cue: 1, Target: Offset{}}, {Cue: 3, Target: Offset{Duration: 4 * time.Second}}}
+
+	count := 0
+	for range Resync(seqOf(subs), anchors, FrameRate{}) {
+		count++
+		break
+	}
+
+	if count != 1 {
+		t.Errorf("expected to stop after 1 cue, got %d", count)
+	}
+}
@@ -0,0 +1,582 @@
+package subtitle
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"iter"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SAMI is an HTML-like format, whose <SYNC Start=ms> blocks hold a
+// paragraph per language class, e.g. <P Class=KRCC>. A paragraph shows
+// until the next one of its class, and &nbsp; clears the screen.
+const (
+	samiSync  = "<sync"
+	samiStyle = "STYLE"
+	// samiPrologue is the <STYLE> rule written for paragraphs when the
+	// document was not read from SAMI
+	samiPrologue = "P { margin-left: 8pt; margin-right: 8pt; " +
+		"text-align: center; font-family: Arial; font-weight: normal; " +
+		"color: white; }"
+)
+
+var ErrUnknownTrack = errors.New("unknown track")
+
+var (
+	samiTitlePattern = regexp.MustCompile(`(?is)<title>(.*?)</title>`)
+	samiStylePattern = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	// A class rule, e.g. .ENUSCC { Name: English; lang: en-US; }
+	samiClassPattern = regexp.MustCompile(`\.([\w-]+)\s*\{([^}]*)\}`)
+	samiParaPattern  = regexp.MustCompile(`(?i)<p(\s[^>]*)?>`)
+	samiBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>`)
+	samiNbspPattern  = regexp.MustCompile(`(?i)&nbsp;?`)
+	samiEndPattern   = regexp.MustCompile(
+		`(?i)</(p|sync|body|sami|html)\s*>`,
+	)
+	samiSpacePattern = regexp.MustCompile(`[ \t\r\n]+`)
+	// samiMarkup is the SRT markup with HTML entities
+	samiMarkup = markup{
+		tags:     srtTags,
+		escape:   vttMarkup.escape,
+		unescape: html.UnescapeString,
+	}
+	// samiTrack is written when the document has neither tracks nor a
+	// language
+	samiTrack = Track{ID: "ENUSCC", Name: "English", Language: "en-US"}
+)
+
+// SamiCue holds the properties of a SAMI cue.
+type SamiCue struct {
+	// Track is the ID of the track of the cue, its language class
+	Track string
+}
+
+// samiPara is a paragraph of a SYNC block in one language class.
+type samiPara struct {
+	class string
+	spans []Span
+}
+
+// parseSamiHeader reads the title and the <STYLE> classes from the head of
+// a SAMI file. The style rules are kept as a section for the SAMI writer.
+func parseSamiHeader(doc *Document, head string) {
+	if match := samiTitlePattern.FindStringSubmatch(head); match != nil {
+		doc.Title = html.UnescapeString(strings.TrimSpace(match[1]))
+	}
+
+	match := samiStylePattern.FindStringSubmatch(head)
+	if match == nil {
+		return
+	}
+
+	style := Section{Name: samiStyle}
+
+	for line := range strings.SplitSeq(match[1], "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "<!--" || line == "-->" {
+			continue
+		}
+
+		style.Lines = append(style.Lines, line)
+	}
+
+	doc.Sections = append(doc.Sections, style)
+
+	for _, rule := range samiClassPattern.FindAllStringSubmatch(match[1], -1) {
+		track := Track{ID: rule[1]}
+
+		for property := range strings.SplitSeq(rule[2], ";") {
+			key, value, found := strings.Cut(property, ":")
+			if !found {
+				continue
+			}
+
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "name":
+				track.Name = strings.TrimSpace(value)
+			case "lang":
+				track.Language = strings.TrimSpace(value)
+			}
+		}
+
+		doc.Tracks = append(doc.Tracks, track)
+	}
+}
+
+// parseSamiSync parses the start time of a SYNC tag at the start of text,
+// returning the text after the tag.
+func parseSamiSync(text string) (time.Duration, string, error) {
+	end := strings.Index(text, ">")
+	if end < 0 {
+		return 0, "", errors.New("missing > after <SYNC")
+	}
+
+	attrs := parseTagAttributes(text[len(samiSync):end])
+
+	value, found := attrs["start"]
+	if !found {
+		return 0, "", errors.New("missing Start in <SYNC> tag")
+	}
+
+	ms, err := strconv.ParseUint(value, 10, 63)
+	if err != nil {
+		return 0, "", &valueError{field: "start time", value: value, err: err}
+	}
+
+	return time.Duration(ms) * time.Millisecond, text[end+1:], nil
+}
+
+// parseSamiParas splits the content of a SYNC block into its paragraphs.
+// Text before the first <P> tag has no class.
+func parseSamiParas(content string) []samiPara {
+	var paras []samiPara
+
+	add := func(class, text string) {
+		// HTML whitespace, with <br> breaking lines
+		text = samiNbspPattern.ReplaceAllLiteralString(text, " ")
+		text = samiEndPattern.ReplaceAllLiteralString(text, "")
+		text = samiSpacePattern.ReplaceAllLiteralString(text, " ")
+		text = samiBreakPattern.ReplaceAllLiteralString(text, "\n")
+
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(line)
+		}
+
+		text = strings.TrimSpace(strings.Join(lines, "\n"))
+		paras = append(paras, samiPara{
+			class: class,
+			spans: parseTaggedText(text, samiMarkup),
+		})
+	}
+
+	tags := samiParaPattern.FindAllStringSubmatchIndex(content, -1)
+
+	if len(tags) == 0 || strings.TrimSpace(content[:tags[0][0]]) != "" {
+		end := len(content)
+		if len(tags) > 0 {
+			end = tags[0][0]
+		}
+
+		add("", content[:end])
+	}
+
+	for i, tag := range tags {
+		end := len(content)
+		if i+1 < len(tags) {
+			end = tags[i+1][0]
+		}
+
+		var class string
+		if tag[2] >= 0 {
+			class = parseTagAttributes(content[tag[2]:tag[3]])["class"]
+		}
+
+		add(class, content[tag[1]:end])
+	}
+
+	return paras
+}
+
+// selectSamiTrack returns the class of the track selected by name, which is
+// either a class or the language of one.
+func selectSamiTrack(doc *Document, name string) string {
+	for _, track := range doc.Tracks {
+		if strings.EqualFold(track.ID, name) ||
+			strings.EqualFold(track.Language, name) {
+			doc.Language = track.Language
+			return track.ID
+		}
+	}
+
+	return name
+}
+
+func newSamiSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		defer stop()
+
+		var (
+			reader = &lineReader{next: next, name: config.Name}
+			errs   = &errorPolicy{lenient: config.Lenient}
+			doc    = config.document()
+			// head holds the lines before <BODY> or the first <SYNC>
+			head   strings.Builder
+			inBody bool
+			// start and content of the SYNC block being read, which is
+			// skipped when malformed
+			start   time.Duration
+			content strings.Builder
+			inSync  bool
+			// selected is the class read, or empty for all of them
+			selected string
+			// classes seen, and whether the selected one was among them
+			classes = make(map[string]bool)
+			matched bool
+			// pending are the cues waiting for their end by class, and
+			// ended the ones waiting for earlier pending cues
+			pending = make(map[string]*Subtitle)
+			ended   []Subtitle
+		)
+
+		// flush yields the ended cues starting before every pending one
+		flush := func() bool {
+			slices.SortStableFunc(ended, func(a, b Subtitle) int {
+				return cmp.Or(
+					cmp.Compare(a.Start, b.Start),
+					cmp.Compare(a.Sami.Track, b.Sami.Track),
+				)
+			})
+
+			earliest := time.Duration(math.MaxInt64)
+			for _, open := range pending {
+				earliest = min(earliest, open.Start)
+			}
+
+			n := 0
+			for _, sub := range ended {
+				if sub.Start > earliest {
+					break
+				}
+
+				if !yield(sub, nil) {
+					return false
+				}
+
+				n++
+			}
+
+			ended = ended[n:]
+
+			return true
+		}
+
+		// endSync ends the cues of the classes in the SYNC block read
+		endSync := func() bool {
+			if !inSync {
+				return true
+			}
+
+			inSync = false
+
+			for _, para := range parseSamiParas(content.String()) {
+				classes[para.class] = true
+
+				// Without declared tracks, the first class is read alone
+				if selected == "" && config.SingleTrack {
+					selected = para.class
+				}
+
+				if selected != "" && !strings.EqualFold(para.class, selected) {
+					continue
+				}
+
+				matched = true
+
+				if open := pending[para.class]; open != nil {
+					if start >= open.Start {
+						open.End = start
+					}
+
+					ended = append(ended, *open)
+					delete(pending, para.class)
+				}
+
+				text := spansText(para.spans)
+				if strings.TrimSpace(text) == "" {
+					continue
+				}
+
+				pending[para.class] = &Subtitle{
+					Start: start,
+					End:   start + config.displayTime(),
+					Text:  text,
+					Spans: styledText(para.spans),
+					Sami:  SamiCue{Track: para.class},
+				}
+			}
+
+			content.Reset()
+
+			return flush()
+		}
+
+		for {
+			line, ok := reader.readLine()
+			if !ok {
+				break
+			}
+
+			if !inBody {
+				lower := strings.ToLower(line)
+
+				body := strings.Index(lower, "<body")
+				if body < 0 {
+					body = strings.Index(lower, samiSync)
+				}
+
+				if body < 0 {
+					head.WriteString(line + "\n")
+					continue
+				}
+
+				head.WriteString(line[:body])
+				parseSamiHeader(doc, head.String())
+
+				switch {
+				case config.Track != "":
+					selected = selectSamiTrack(doc, config.Track)
+				case config.SingleTrack && len(doc.Tracks) > 0:
+					selected = doc.Tracks[0].ID
+					doc.Language = doc.Tracks[0].Language
+				case len(doc.Tracks) == 1:
+					doc.Language = doc.Tracks[0].Language
+				}
+
+				inBody, line = true, line[body:]
+			}
+
+			for line != "" {
+				at := strings.Index(strings.ToLower(line), samiSync)
+				if at < 0 {
+					if inSync {
+						content.WriteString(line + "\n")
+					}
+
+					break
+				}
+
+				if inSync {
+					content.WriteString(line[:at])
+				}
+
+				if !endSync() {
+					return
+				}
+
+				sync, rest, err := parseSamiSync(line[at:])
+				if err != nil {
+					parseErr := reader.parseError(err)
+					parseErr.Column += len(reader.last) - len(line[at:])
+
+					if !errs.skip(parseErr) {
+						yield(Subtitle{}, parseErr)
+						return
+					}
+
+					// Skip the malformed tag
+					line = line[at+len(samiSync):]
+
+					continue
+				}
+
+				start, line, inSync = sync, rest, true
+			}
+		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading sami subtitle: %w", reader.err),
+			)
+
+			return
+		}
+
+		if !endSync() {
+			return
+		}
+
+		// The last cues are shown for the display time
+		for _, open := range pending {
+			ended = append(ended, *open)
+		}
+
+		clear(pending)
+
+		if !flush() {
+			return
+		}
+
+		if config.Track != "" && !matched && len(classes) > 0 {
+			names := slices.Sorted(maps.Keys(classes))
+			yield(Subtitle{}, fmt.Errorf(
+				"%w %q, the file has %s",
+				ErrUnknownTrack,
+				config.Track,
+				strings.Join(names, ", "),
+			))
+
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+// samiTracks returns the tracks to declare in the <STYLE> header, those of
+// the document or a single one in the language.
+func samiTracks(doc *Document, lang string) []Track {
+	if len(doc.Tracks) > 0 {
+		return doc.Tracks
+	}
+
+	if lang == "" {
+		return []Track{samiTrack}
+	}
+
+	// e.g. ENUSCC for en-US
+	id := strings.ToUpper(strings.ReplaceAll(lang, "-", "")) + "CC"
+
+	return []Track{{ID: id, Name: lang, Language: lang}}
+}
+
+func writeSamiHeader(w io.Writer, doc *Document, tracks []Track) error {
+	if _, err := fmt.Fprintln(w, "<SAMI>\n<HEAD>"); err != nil {
+		return err
+	}
+
+	if doc.Title != "" {
+		_, err := fmt.Fprintf(
+			w,
+			"<TITLE>%s</TITLE>\n",
+			html.EscapeString(doc.Title),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, sections := doc.metadata(SamiFormat)
+
+	var rules []string
+
+	for _, section := range sections {
+		if section.Name == samiStyle {
+			rules = append(rules, section.Lines...)
+		}
+	}
+
+	if rules == nil {
+		rules = append(rules, samiPrologue)
+
+		for _, track := range tracks {
+			rules = append(rules, fmt.Sprintf(
+				".%s { Name: %s; lang: %s; SAMIType: CC; }",
+				track.ID,
+				cmp.Or(track.Name, track.ID),
+				track.Language,
+			))
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"<STYLE TYPE=\"text/css\">\n<!--\n%s\n-->\n</STYLE>\n</HEAD>\n<BODY>\n",
+		strings.Join(rules, "\n"),
+	)
+
+	return err
+}
+
+func writeSamiSync(
+	w io.Writer,
+	start time.Duration,
+	class, text string,
+) error {
+	_, err := fmt.Fprintf(
+		w,
+		"<SYNC Start=%d><P Class=%s>%s\n",
+		start.Milliseconds(),
+		class,
+		text,
+	)
+
+	return err
+}
+
+// newSamiPrinter returns a SAMI printer. Cues are written as SYNC blocks
+// of their track, and the end of a cue as a block clearing the track with
+// &nbsp;, unless the next cue of the track starts by then.
+func newSamiPrinter(writer io.Writer, config PrinterConfig) Printer {
+	var (
+		// class of cues without a track
+		class string
+		// clears are the pending ends of the tracks by class
+		clears = make(map[string]time.Duration)
+	)
+
+	// writeClears writes the clears due by the time, every one when
+	// negative, except for the class starting a cue at the time
+	writeClears := func(w io.Writer, at time.Duration, starting string) error {
+		due := slices.SortedFunc(maps.Keys(clears), func(a, b string) int {
+			return cmp.Or(cmp.Compare(clears[a], clears[b]), cmp.Compare(a, b))
+		})
+
+		for _, track := range due {
+			end := clears[track]
+			if at >= 0 && end > at && track != starting {
+				continue
+			}
+
+			delete(clears, track)
+
+			if at >= 0 && track == starting && end >= at {
+				continue
+			}
+
+			if err := writeSamiSync(w, end, track, "&nbsp;"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return &printer{
+		writer: writer,
+		header: func(w io.Writer, _ *Subtitle) error {
+			doc := config.document()
+			tracks := samiTracks(doc, config.language())
+			class = tracks[0].ID
+
+			return writeSamiHeader(w, doc, tracks)
+		},
+		write: func(w io.Writer, sub Subtitle) error {
+			track := cmp.Or(sub.Sami.Track, class)
+			if err := writeClears(w, sub.Start, track); err != nil {
+				return err
+			}
+
+			text := formatTaggedText(sub.spans(), samiMarkup)
+			text = strings.ReplaceAll(text, "\n", "<br>")
+			clears[track] = sub.End
+
+			return writeSamiSync(w, sub.Start, track, text)
+		},
+		footer: func(w io.Writer) error {
+			if err := writeClears(w, -1, ""); err != nil {
+				return err
+			}
+
+			_, err := fmt.Fprintln(w, "</BODY>\n</SAMI>")
+
+			return err
+		},
+	}
+}
@@ -0,0 +1,273 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const samiInput = "<SAMI>\r\n" +
+	"<HEAD>\r\n" +
+	"<TITLE>Movie &amp; more</TITLE>\r\n" +
+	"<STYLE TYPE=\"text/css\">\r\n" +
+	"<!--\r\n" +
+	"P { font-family: Arial; color: white; }\r\n" +
+	".ENUSCC { Name: English; lang: en-US; SAMIType: CC; }\r\n" +
+	".KRCC { Name: Korean; lang: ko-KR; SAMIType: CC; }\r\n" +
+	"-->\r\n" +
+	"</STYLE>\r\n" +
+	"</HEAD>\r\n" +
+	"<BODY>\r\n" +
+	"<SYNC Start=1000><P Class=ENUSCC>Hello\r\n" +
+	"<i>there</i><br>you\r\n" +
+	"<SYNC Start=1500><P Class=KRCC>안녕하세요\r\n" +
+	"<SYNC Start=3000><P Class=ENUSCC>&nbsp;\r\n" +
+	"<SYNC Start=4000><P Class=KRCC>&nbsp;<P Class=ENUSCC>Fish &amp; chips\r\n" +
+	"</BODY>\r\n" +
+	"</SAMI>\r\n"
+
+func TestNewSubtitlesIter_SamiFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		track  string
+		single bool
+		want   []Subtitle
+		lang   string
+	}{
+		{
+			name: "every track",
+			want: []Subtitle{
+				{
+					Start: time.Second,
+					End:   3 * time.Second,
+					Text:  "Hello there\nyou",
+					Spans: &StyledText{{Text: "Hello "}, {Text: "there", Italic: true}, {Text: "\nyou"}},
+					Sami:  SamiCue{Track: "ENUSCC"},
+				},
+				{Start: 1500 * time.Millisecond, End: 4 * time.Second, Text: "안녕하세요", Sami: SamiCue{Track: "KRCC"}},
+				{Start: 4 * time.Second, End: 7 * time.Second, Text: "Fish & chips", Sami: SamiCue{Track: "ENUSCC"}},
+			},
+		},
+		{
+			name:  "class",
+			track: "krcc",
+			want:  []Subtitle{{Start: 1500 * time.Millisecond, End: 4 * time.Second, Text: "안녕하세요", Sami: SamiCue{Track: "KRCC"}}},
+			lang:  "ko-KR",
+		},
+		{
+			name:  "language",
+			track: "en-US",
+			want: []Subtitle{
+				{
+					Start: time.Second,
+					End:   3 * time.Second,
+					Text:  "Hello there\nyou",
+					Spans: &StyledText{{Text: "Hello "}, {Text: "there", Italic: true}, {Text: "\nyou"}},
+					Sami:  SamiCue{Track: "ENUSCC"},
+				},
+				{Start: 4 * time.Second, End: 7 * time.Second, Text: "Fish & chips", Sami: SamiCue{Track: "ENUSCC"}},
+			},
+			lang: "en-US",
+		},
+		{
+			name:   "first track",
+			single: true,
+			want: []Subtitle{
+				{
+					Start: time.Second,
+					End:   3 * time.Second,
+					Text:  "Hello there\nyou",
+					Spans: &StyledText{{Text: "Hello "}, {Text: "there", Italic: true}, {Text: "\nyou"}},
+					Sami:  SamiCue{Track: "ENUSCC"},
+				},
+				{Start: 4 * time.Second, End: 7 * time.Second, Text: "Fish & chips", Sami: SamiCue{Track: "ENUSCC"}},
+			},
+			lang: "en-US",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				doc  Document
+				subs []Subtitle
+			)
+
+			config := ReaderConfig{Document: &doc, Track: tt.track, SingleTrack: tt.single}
+			for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(samiInput), SamiFormat, config) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				subs = append(subs, sub)
+			}
+
+			if !reflect.DeepEqual(subs, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, subs)
+			}
+
+			wantTracks := []Track{
+				{ID: "ENUSCC", Name: "English", Language: "en-US"},
+				{ID: "KRCC", Name: "Korean", Language: "ko-KR"},
+			}
+			if doc.Title != "Movie & more" || doc.Language != tt.lang || !reflect.DeepEqual(doc.Tracks, wantTracks) {
+				t.Errorf("unexpected document %+v", doc)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_SamiFormat_FirstUndeclaredTrack(t *testing.T) {
+	input := "<SAMI><BODY>\n" +
+		"<SYNC Start=1000><P Class=KRCC>안녕<P Class=ENUSCC>Hello\n" +
+		"<SYNC Start=2000><P Class=ENUSCC>Bye\n" +
+		"</BODY></SAMI>\n"
+
+	var texts []string
+
+	config := ReaderConfig{SingleTrack: true}
+	for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(input), SamiFormat, config) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if !reflect.DeepEqual(texts, []string{"안녕"}) {
+		t.Errorf("expected the first class only, got %q", texts)
+	}
+}
+
+func TestNewSubtitlesIter_SamiFormat_UnknownTrack(t *testing.T) {
+	config := ReaderConfig{Track: "FRCC"}
+
+	var got error
+
+	for _, err := range NewSubtitlesIterWithConfig(strings.NewReader(samiInput), SamiFormat, config) {
+		if err != nil {
+			got = err
+		}
+	}
+
+	if !errors.Is(got, ErrUnknownTrack) || !strings.Contains(got.Error(), "ENUSCC, KRCC") {
+		t.Errorf("expected ErrUnknownTrack listing the classes, got %v", got)
+	}
+}
+
+func TestNewSubtitlesIter_SamiFormat_Errors(t *testing.T) {
+	input := "<SAMI><BODY>\n" +
+		"<SYNC Start=1000><P>First\n" +
+		"  <SYNC Start=x><P>Broken\n" +
+		"<SYNC Start=3000><P>Last\n"
+
+	var parseErr *ParseError
+
+	for _, err := range NewSubtitlesIter(strings.NewReader(input), SamiFormat) {
+		if err != nil && !errors.As(err, &parseErr) {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+	}
+
+	if parseErr == nil || parseErr.Line != 3 || parseErr.Column != 3 {
+		t.Fatalf("expected an error at 3:3, got %v", parseErr)
+	}
+
+	var (
+		texts   []string
+		skipped *SkippedCuesError
+	)
+
+	subs := NewSubtitlesIterWithConfig(strings.NewReader(input), SamiFormat, ReaderConfig{Lenient: true})
+	for sub, err := range subs {
+		if errors.As(err, &skipped) {
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if !reflect.DeepEqual(texts, []string{"First", "Last"}) || skipped == nil || len(skipped.Errors) != 1 {
+		t.Errorf("expected 2 cues and 1 skipped, got %q, %v", texts, skipped)
+	}
+}
+
+func TestNewSubtitlePrinter_SamiFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(&buf, SamiFormat, PrinterConfig{Document: &Document{Language: "pl"}})
+
+	subs := []Subtitle{
+		{Start: time.Second, End: 2 * time.Second, Text: "One\nTwo", Spans: &StyledText{{Text: "One", Bold: true}, {Text: "\nTwo"}}},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "Back to back"},
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "<Fish & chips>"},
+	}
+	for _, sub := range subs {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "<SAMI>\n<HEAD>\n<STYLE TYPE=\"text/css\">\n<!--\n" + samiPrologue + "\n" +
+		".PLCC { Name: pl; lang: pl; SAMIType: CC; }\n-->\n</STYLE>\n</HEAD>\n<BODY>\n" +
+		"<SYNC Start=1000><P Class=PLCC><b>One</b><br>Two\n" +
+		"<SYNC Start=2000><P Class=PLCC>Back to back\n" +
+		"<SYNC Start=3000><P Class=PLCC>&nbsp;\n" +
+		"<SYNC Start=5000><P Class=PLCC>&lt;Fish &amp; chips&gt;\n" +
+		"<SYNC Start=6000><P Class=PLCC>&nbsp;\n" +
+		"</BODY>\n</SAMI>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestNewSubtitlePrinter_SamiFormat_RoundTrip(t *testing.T) {
+	var (
+		doc Document
+		buf bytes.Buffer
+	)
+
+	printer := NewSubtitlePrinterWithConfig(&buf, SamiFormat, PrinterConfig{Document: &doc})
+
+	config := ReaderConfig{Document: &doc}
+	for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(samiInput), SamiFormat, config) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "<SAMI>\n<HEAD>\n<TITLE>Movie &amp; more</TITLE>\n<STYLE TYPE=\"text/css\">\n<!--\n" +
+		"P { font-family: Arial; color: white; }\n" +
+		".ENUSCC { Name: English; lang: en-US; SAMIType: CC; }\n" +
+		".KRCC { Name: Korean; lang: ko-KR; SAMIType: CC; }\n" +
+		"-->\n</STYLE>\n</HEAD>\n<BODY>\n" +
+		"<SYNC Start=1000><P Class=ENUSCC>Hello <i>there</i><br>you\n" +
+		"<SYNC Start=1500><P Class=KRCC>안녕하세요\n" +
+		"<SYNC Start=3000><P Class=ENUSCC>&nbsp;\n" +
+		"<SYNC Start=4000><P Class=KRCC>&nbsp;\n" +
+		"<SYNC Start=4000><P Class=ENUSCC>Fish &amp; chips\n" +
+		"<SYNC Start=7000><P Class=ENUSCC>&nbsp;\n" +
+		"</BODY>\n</SAMI>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
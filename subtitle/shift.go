@@ -0,0 +1,189 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShiftPolicy decides what happens to cues moved before zero.
+type ShiftPolicy uint8
+
+const (
+	// ShiftClamp moves the start of cues straddling zero to zero, and leaves
+	// out cues ending by zero
+	ShiftClamp ShiftPolicy = iota
+	// ShiftDrop leaves out cues starting before zero
+	ShiftDrop
+	// ShiftError stops at the first cue starting before zero
+	ShiftError
+)
+
+var shiftPolicyNames = [...]string{
+	ShiftClamp: "clamp",
+	ShiftDrop:  "drop",
+	ShiftError: "error",
+}
+
+var (
+	ErrInvalidOffset = errors.New("invalid offset")
+	ErrNegativeTime  = errors.New("cue shifted before zero")
+)
+
+func (p ShiftPolicy) String() string {
+	if int(p) < len(shiftPolicyNames) {
+		return shiftPolicyNames[p]
+	}
+
+	return fmt.Sprintf("ShiftPolicy(%d)", p)
+}
+
+// ParseShiftPolicy returns the policy with the given name: clamp, drop or
+// error.
+func ParseShiftPolicy(name string) (ShiftPolicy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	for policy, policyName := range shiftPolicyNames {
+		if policyName == name {
+			return ShiftPolicy(policy), nil
+		}
+	}
+
+	return ShiftClamp, fmt.Errorf(
+		"unknown shift policy %q (supported: %s)",
+		name,
+		strings.Join(shiftPolicyNames[:], ", "),
+	)
+}
+
+// Offset is a signed time offset, given as a duration or as a number of
+// frames whose duration depends on the frame rate of the cues.
+type Offset struct {
+	Duration time.Duration
+	// Frames, when not zero, is the offset in frames instead of Duration
+	Frames int64
+}
+
+// At returns the duration of the offset with frames counted at rate,
+// DefaultFrameRate when zero.
+func (o Offset) At(rate FrameRate) time.Duration {
+	if o.Frames == 0 {
+		return o.Duration
+	}
+
+	return rate.orDefault().frameToDuration(o.Frames)
+}
+
+func (o Offset) String() string {
+	if o.Frames != 0 {
+		return strconv.FormatInt(o.Frames, 10) + "f"
+	}
+
+	return o.Duration.String()
+}
+
+// ParseOffset parses a signed offset given as a Go duration ("+2.5s",
+// "-1m30s"), a clock time ("-00:00:01,200", "01:02.5") or a number of frames
+// ("-48f"), which are kept as frames until the frame rate is known.
+func ParseOffset(value string) (Offset, error) {
+	value = strings.TrimSpace(value)
+
+	sign, unsigned := time.Duration(1), value
+	if rest, found := strings.CutPrefix(value, "-"); found {
+		sign, unsigned = -1, rest
+	} else {
+		unsigned = strings.TrimPrefix(value, "+")
+	}
+
+	var (
+		offset time.Duration
+		frames uint64
+		err    error
+	)
+
+	switch {
+	case unsigned == "" || strings.ContainsAny(unsigned, "+-"):
+		err = ErrInvalidOffset
+	case strings.HasSuffix(unsigned, "f"):
+		frames, err = strconv.ParseUint(
+			strings.TrimSuffix(unsigned, "f"),
+			10,
+			32,
+		)
+	case strings.Contains(unsigned, ":"):
+		if strings.Count(unsigned, ":") == 1 {
+			unsigned = "00:" + unsigned
+		}
+
+		offset, err = parseSrtDuration(unsigned)
+	default:
+		offset, err = time.ParseDuration(unsigned)
+	}
+
+	if err != nil {
+		return Offset{}, fmt.Errorf("%w %q", ErrInvalidOffset, value)
+	}
+
+	return Offset{
+		Duration: sign * offset,
+		Frames:   int64(sign) * int64(frames),
+	}, nil
+}
+
+// Shift moves every cue by offset, with frames counted at rate, applying
+// policy to cues that would start before zero. Errors of subs are passed
+// on.
+func Shift(
+	subs iter.Seq2[Subtitle, error],
+	offset Offset,
+	rate FrameRate,
+	policy ShiftPolicy,
+) iter.Seq2[Subtitle, error] {
+	delta := offset.At(rate)
+
+	return func(yield func(Subtitle, error) bool) {
+		for sub, err := range subs {
+			if err != nil {
+				if !yield(sub, err) {
+					return
+				}
+
+				continue
+			}
+
+			start := sub.Start
+			sub.Start += delta
+			sub.End += delta
+
+			if sub.Start < 0 {
+				switch policy {
+				case ShiftDrop:
+					continue
+				case ShiftError:
+					yield(Subtitle{}, fmt.Errorf(
+						"%w: cue at %v moved to %v",
+						ErrNegativeTime,
+						start,
+						sub.Start,
+					))
+
+					return
+				default:
+					// Cues ending by zero would not be shown at all
+					if sub.End <= 0 {
+						continue
+					}
+
+					sub.Start = 0
+				}
+			}
+
+			if !yield(sub, nil) {
+				return
+			}
+		}
+	}
+}
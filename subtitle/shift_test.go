@@ -0,0 +1,177 @@
+package subtitle
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Offset
+	}{
+		{"+2.5s", Offset{Duration: 2500 * time.Millisecond}},
+		{"2.5s", Offset{Duration: 2500 * time.Millisecond}},
+		{"-1m30s", Offset{Duration: -90 * time.Second}},
+		{"500ms", Offset{Duration: 500 * time.Millisecond}},
+		{"-00:00:01,200", Offset{Duration: -1200 * time.Millisecond}},
+		{"+01:02:03.5", Offset{Duration: time.Hour + 2*time.Minute + 3500*time.Millisecond}},
+		{"01:02.5", Offset{Duration: time.Minute + 2500*time.Millisecond}},
+		{"-48f", Offset{Frames: -48}},
+		{" +25f ", Offset{Frames: 25}},
+		{"0s", Offset{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOffset(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOffset_At(t *testing.T) {
+	tests := []struct {
+		offset Offset
+		rate   FrameRate
+		want   time.Duration
+	}{
+		{Offset{Duration: -1200 * time.Millisecond}, FrameRate{Num: 25, Den: 1}, -1200 * time.Millisecond},
+		{Offset{Frames: -48}, FrameRate{}, -2002 * time.Millisecond},
+		{Offset{Frames: -48}, FrameRate{Num: 24, Den: 1}, -2 * time.Second},
+		{Offset{Frames: -48}, FrameRate{Num: 25, Den: 1}, -1920 * time.Millisecond},
+		{Offset{Frames: 25}, FrameRate{Num: 25, Den: 1}, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.offset.String()+"@"+tt.rate.String(), func(t *testing.T) {
+			if got := tt.offset.At(tt.rate); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseOffset_Errors(t *testing.T) {
+	for _, value := range []string{"", "+", "2.5", "--2s", "+-2s", "xf", "-1.5f", "1:xx", "1:2:3:4"} {
+		t.Run(value, func(t *testing.T) {
+			if _, err := ParseOffset(value); !errors.Is(err, ErrInvalidOffset) {
+				t.Errorf("expected ErrInvalidOffset, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseShiftPolicy(t *testing.T) {
+	for _, policy := range []ShiftPolicy{ShiftClamp, ShiftDrop, ShiftError} {
+		got, err := ParseShiftPolicy(policy.String())
+		if err != nil || got != policy {
+			t.Errorf("%v: expected round trip, got %v, %v", policy, got, err)
+		}
+	}
+
+	if _, err := ParseShiftPolicy("wrap"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestShift(t *testing.T) {
+	subs := []Subtitle{
+		{Start: 500 * time.Millisecond, End: 1500 * time.Millisecond, Text: "Before"},
+		{Start: time.Second, End: 2 * time.Second, Text: "Ending at zero"},
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "Across"},
+		{Start: 4 * time.Second, End: 5 * time.Second, Text: "After"},
+	}
+
+	seq := func(yield func(Subtitle, error) bool) {
+		for _, sub := range subs {
+			if !yield(sub, nil) {
+				return
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		policy  ShiftPolicy
+		want    []Subtitle
+		wantErr error
+	}{
+		{
+			name:   "clamp",
+			policy: ShiftClamp,
+			want: []Subtitle{
+				{Start: 0, End: time.Second, Text: "Across"},
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "After"},
+			},
+		},
+		{
+			name:   "drop",
+			policy: ShiftDrop,
+			want: []Subtitle{
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "After"},
+			},
+		},
+		{
+			name:    "error",
+			policy:  ShiftError,
+			wantErr: ErrNegativeTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Subtitle
+
+			for sub, err := range Shift(seq, Offset{Duration: -2 * time.Second}, FrameRate{}, tt.policy) {
+				if err != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("expected %v, got %v", tt.wantErr, err)
+					}
+
+					break
+				}
+
+				got = append(got, sub)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShift_PassesErrors(t *testing.T) {
+	skipped := &SkippedCuesError{}
+	seq := func(yield func(Subtitle, error) bool) {
+		if yield(Subtitle{Start: time.Second, End: 2 * time.Second}, nil) {
+			yield(Subtitle{}, skipped)
+		}
+	}
+
+	var errs []error
+
+	for sub, err := range Shift(seq, Offset{Frames: 25}, FrameRate{Num: 25, Den: 1}, ShiftClamp) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if sub.Start != 2*time.Second || sub.End != 3*time.Second {
+			t.Errorf("unexpected shifted cue %+v", sub)
+		}
+	}
+
+	if len(errs) != 1 || errs[0] != error(skipped) {
+		t.Errorf("expected the upstream error, got %v", errs)
+	}
+}
@@ -0,0 +1,593 @@
+package subtitle
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Span is a run of subtitle text sharing one style. Line breaks are kept in
+// the text as newlines.
+type Span struct {
+	Text      string
+	Italic    bool
+	Bold      bool
+	Underline bool
+	// Color is "#RRGGBB" or a colour name, empty for the default
+	Color string
+	// Font is a font family, empty for the default
+	Font string
+	// Size is a font size, zero for the default
+	Size int
+	// Ruby is an annotation shown above the text, e.g. furigana
+	Ruby string
+	// Voice is the speaker of the text
+	Voice string
+}
+
+// StyledText is the text of a cue as styled spans.
+type StyledText []Span
+
+// style returns the span without its text, so that styles can be compared.
+func (s Span) style() Span {
+	s.Text = ""
+	return s
+}
+
+func (s Span) isPlain() bool {
+	return s.style() == Span{}
+}
+
+// mergeSpans joins neighbouring spans of one style and drops empty ones.
+func mergeSpans(spans []Span) []Span {
+	var merged []Span
+
+	for _, span := range spans {
+		if span.Text == "" {
+			continue
+		}
+
+		// Each annotation belongs to its own text
+		if n := len(merged); n > 0 && span.Ruby == "" &&
+			merged[n-1].style() == span.style() {
+			merged[n-1].Text += span.Text
+			continue
+		}
+
+		merged = append(merged, span)
+	}
+
+	return merged
+}
+
+// styledText returns the merged spans, or nil when none is styled, so that
+// plain text is only kept in Subtitle.Text.
+func styledText(spans []Span) *StyledText {
+	styled := StyledText(mergeSpans(spans))
+
+	for _, span := range styled {
+		if !span.isPlain() {
+			return &styled
+		}
+	}
+
+	return nil
+}
+
+// spansText returns the text of the spans without styling.
+func spansText(spans []Span) string {
+	var b strings.Builder
+
+	for _, span := range spans {
+		b.WriteString(span.Text)
+	}
+
+	return b.String()
+}
+
+// spans returns the styled text of the cue, a single plain span when the
+// cue has no styling.
+func (s Subtitle) spans() []Span {
+	if s.Spans != nil {
+		return *s.Spans
+	}
+
+	return []Span{{Text: s.Text}}
+}
+
+// flattenRuby appends annotations in parentheses to their text, e.g.
+// "漢字(かんじ)", for formats without ruby.
+func flattenRuby(spans []Span) []Span {
+	flat := make([]Span, 0, len(spans))
+
+	for _, span := range spans {
+		ruby := span.Ruby
+		span.Ruby = ""
+		flat = append(flat, span)
+
+		if ruby != "" {
+			span.Text = "(" + ruby + ")"
+			flat = append(flat, span)
+		}
+	}
+
+	return mergeSpans(flat)
+}
+
+// spanLines splits the spans at line breaks, one slice of spans per line.
+func spanLines(spans []Span) [][]Span {
+	lines := [][]Span{nil}
+
+	for _, span := range spans {
+		parts := strings.Split(span.Text, "\n")
+
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+
+			if part != "" {
+				span.Text = part
+				lines[len(lines)-1] = append(lines[len(lines)-1], span)
+			}
+		}
+	}
+
+	return lines
+}
+
+// commonStyle returns the style shared by all spans.
+func commonStyle(spans []Span) Span {
+	if len(spans) == 0 {
+		return Span{}
+	}
+
+	common := spans[0].style()
+
+	for _, span := range spans[1:] {
+		common.Italic = common.Italic && span.Italic
+		common.Bold = common.Bold && span.Bold
+		common.Underline = common.Underline && span.Underline
+
+		if common.Color != span.Color {
+			common.Color = ""
+		}
+
+		if common.Font != span.Font {
+			common.Font = ""
+		}
+
+		if common.Size != span.Size {
+			common.Size = 0
+		}
+
+		if common.Ruby != span.Ruby {
+			common.Ruby = ""
+		}
+
+		if common.Voice != span.Voice {
+			common.Voice = ""
+		}
+	}
+
+	return common
+}
+
+// namedColors are the colour classes predefined by WebVTT.
+var namedColors = map[string]string{
+	"white":   "FFFFFF",
+	"lime":    "00FF00",
+	"cyan":    "00FFFF",
+	"red":     "FF0000",
+	"yellow":  "FFFF00",
+	"magenta": "FF00FF",
+	"blue":    "0000FF",
+	"black":   "000000",
+}
+
+// colorHex returns the colour as RRGGBB, for "#RRGGBB" and named colours.
+func colorHex(color string) (string, bool) {
+	if hex, ok := namedColors[strings.ToLower(color)]; ok {
+		return hex, true
+	}
+
+	hex, ok := strings.CutPrefix(normalizeColor(color), "#")
+
+	return hex, ok && len(hex) == 6
+}
+
+// colorName returns the WebVTT colour class of the colour.
+func colorName(color string) (string, bool) {
+	hex, ok := colorHex(color)
+	if !ok {
+		return "", false
+	}
+
+	for name, value := range namedColors {
+		if value == hex {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// normalizeColor writes hexadecimal colours as "#RRGGBB", keeping names.
+func normalizeColor(value string) string {
+	value = strings.TrimSpace(value)
+
+	if hex, ok := strings.CutPrefix(value, "#"); ok && len(hex) == 6 {
+		if _, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return "#" + strings.ToUpper(hex)
+		}
+	}
+
+	return value
+}
+
+// htmlTag is an opening tag of HTML-style markup, e.g. {"font",
+// ` color="#FF0000"`} or {"c", ".yellow"}.
+type htmlTag struct {
+	name  string
+	attrs string
+}
+
+// markup is a dialect of HTML-style text markup.
+type markup struct {
+	// tags returns the tags giving the style of a span
+	tags func(Span) []htmlTag
+	// escape and unescape convert text to and from the markup when set
+	escape   func(string) string
+	unescape func(string) string
+	// ruby writes <ruby> annotations, which are flattened otherwise
+	ruby bool
+}
+
+var (
+	srtMarkup = markup{tags: srtTags}
+	vttMarkup = markup{
+		tags: vttTags,
+		escape: strings.NewReplacer(
+			"&", "&amp;",
+			"<", "&lt;",
+			">", "&gt;",
+		).Replace,
+		unescape: html.UnescapeString,
+		ruby:     true,
+	}
+)
+
+// srtTags returns the tags of SRT markup giving the style of the span.
+func srtTags(span Span) []htmlTag {
+	var tags []htmlTag
+
+	var font strings.Builder
+	if span.Font != "" {
+		fmt.Fprintf(&font, ` face="%s"`, span.Font)
+	}
+
+	if span.Color != "" {
+		fmt.Fprintf(&font, ` color="%s"`, span.Color)
+	}
+
+	if span.Size != 0 {
+		fmt.Fprintf(&font, ` size="%d"`, span.Size)
+	}
+
+	if font.Len() > 0 {
+		tags = append(tags, htmlTag{name: "font", attrs: font.String()})
+	}
+
+	return append(tags, emphasisTags(span)...)
+}
+
+// vttTags returns the tags of WebVTT markup giving the style of the span.
+// Colours are limited to the predefined classes, and fonts are left to
+// style sheets.
+func vttTags(span Span) []htmlTag {
+	var tags []htmlTag
+
+	if span.Voice != "" {
+		tags = append(tags, htmlTag{name: "v", attrs: " " + span.Voice})
+	}
+
+	if name, ok := colorName(span.Color); ok {
+		tags = append(tags, htmlTag{name: "c", attrs: "." + name})
+	}
+
+	return append(tags, emphasisTags(span)...)
+}
+
+func emphasisTags(span Span) []htmlTag {
+	var tags []htmlTag
+
+	if span.Bold {
+		tags = append(tags, htmlTag{name: "b"})
+	}
+
+	if span.Italic {
+		tags = append(tags, htmlTag{name: "i"})
+	}
+
+	if span.Underline {
+		tags = append(tags, htmlTag{name: "u"})
+	}
+
+	return tags
+}
+
+// formatTaggedText renders the spans in the markup, keeping tags open across
+// spans that share them.
+func formatTaggedText(spans []Span, m markup) string {
+	var (
+		b    strings.Builder
+		open []htmlTag
+	)
+
+	escape := func(text string) string {
+		if m.escape == nil {
+			return text
+		}
+
+		return m.escape(text)
+	}
+
+	if !m.ruby {
+		spans = flattenRuby(spans)
+	}
+
+	for _, span := range spans {
+		want := m.tags(span)
+
+		// Close the tags from the first one the span does not use
+		keep := 0
+		for keep < len(open) && containsTag(want, open[keep]) {
+			keep++
+		}
+
+		for i := len(open) - 1; i >= keep; i-- {
+			fmt.Fprintf(&b, "</%s>", open[i].name)
+		}
+
+		open = open[:keep]
+
+		for _, tag := range want {
+			if !containsTag(open, tag) {
+				fmt.Fprintf(&b, "<%s%s>", tag.name, tag.attrs)
+				open = append(open, tag)
+			}
+		}
+
+		if span.Ruby == "" {
+			b.WriteString(escape(span.Text))
+			continue
+		}
+
+		fmt.Fprintf(
+			&b,
+			"<ruby>%s<rt>%s</rt></ruby>",
+			escape(span.Text),
+			escape(span.Ruby),
+		)
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "</%s>", open[i].name)
+	}
+
+	return b.String()
+}
+
+func containsTag(tags []htmlTag, tag htmlTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTaggedText parses HTML-style markup into spans. It understands the
+// SRT tags, <i>, <b>, <u> and <font> with face, color and size attributes,
+// and the WebVTT ones, <c> with colour classes, <v>, <ruby>, <rt> and <lang>.
+// Other tags and unmatched closing tags are kept as text, except for WebVTT
+// timestamps, which are dropped.
+func parseTaggedText(text string, m markup) []Span {
+	type openTag struct {
+		name  string
+		style Span
+	}
+
+	var (
+		spans []Span
+		stack []openTag
+		style Span
+		// ruby is the annotation being read, inRuby while in <rt>, and
+		// base the index of the first span it annotates
+		ruby   strings.Builder
+		inRuby bool
+		base   int
+	)
+
+	unescape := func(text string) string {
+		if m.unescape == nil {
+			return text
+		}
+
+		return m.unescape(text)
+	}
+
+	emit := func(text string) {
+		if inRuby {
+			ruby.WriteString(unescape(text))
+			return
+		}
+
+		span := style
+		span.Text = unescape(text)
+		spans = append(spans, span)
+	}
+
+	// endRuby attaches the annotation read so far to the last span of its
+	// base text
+	endRuby := func() {
+		if inRuby && len(spans) > base {
+			spans[len(spans)-1].Ruby = ruby.String()
+		}
+
+		ruby.Reset()
+		inRuby = false
+		base = len(spans)
+	}
+
+	for {
+		from := strings.Index(text, "<")
+		if from < 0 {
+			break
+		}
+
+		till := strings.Index(text[from:], ">")
+		if till < 0 {
+			break
+		}
+
+		emit(text[:from])
+
+		tag := text[from : from+till+1]
+		text = text[from+till+1:]
+
+		name, attrs, _ := strings.Cut(tag[1:len(tag)-1], " ")
+		if isVttTimestamp(name) {
+			// Karaoke-style text
+			continue
+		}
+
+		name, class, _ := strings.Cut(strings.ToLower(name), ".")
+
+		if closing, ok := strings.CutPrefix(name, "/"); ok {
+			i := len(stack) - 1
+			for i >= 0 && stack[i].name != closing {
+				i--
+			}
+
+			if i < 0 {
+				emit(tag)
+				continue
+			}
+
+			for _, open := range stack[i:] {
+				if open.name == "rt" {
+					endRuby()
+				}
+			}
+
+			style = stack[i].style
+			stack = stack[:i]
+
+			continue
+		}
+
+		next := style
+
+		switch name {
+		case "i":
+			next.Italic = true
+		case "b":
+			next.Bold = true
+		case "u":
+			next.Underline = true
+		case "font":
+			for key, value := range parseTagAttributes(attrs) {
+				switch key {
+				case "face":
+					next.Font = value
+				case "color":
+					next.Color = normalizeColor(value)
+				case "size":
+					if size, err := strconv.Atoi(value); err == nil {
+						next.Size = size
+					}
+				}
+			}
+		case "c":
+			for class := range strings.SplitSeq(class, ".") {
+				if _, ok := namedColors[class]; ok {
+					next.Color = class
+				}
+			}
+		case "v":
+			next.Voice = unescape(strings.TrimSpace(attrs))
+		case "ruby":
+			base = len(spans)
+		case "rt":
+			inRuby = true
+		case "lang":
+		default:
+			emit(tag)
+			continue
+		}
+
+		stack = append(stack, openTag{name: name, style: style})
+		style = next
+	}
+
+	emit(text)
+
+	if inRuby {
+		endRuby()
+	}
+
+	return mergeSpans(spans)
+}
+
+// isVttTimestamp reports whether the tag name is a WebVTT timestamp, e.g.
+// "00:01.500".
+func isVttTimestamp(name string) bool {
+	_, err := parseVttDuration(name)
+	return err == nil
+}
+
+// parseTagAttributes parses name=value pairs, the values optionally quoted.
+func parseTagAttributes(attrs string) map[string]string {
+	values := make(map[string]string)
+
+	for {
+		attrs = strings.TrimLeft(attrs, " \t/")
+		if attrs == "" {
+			return values
+		}
+
+		end := strings.IndexAny(attrs, "= \t")
+		if end < 0 {
+			values[strings.ToLower(attrs)] = ""
+			return values
+		}
+
+		name := strings.ToLower(attrs[:end])
+		attrs = strings.TrimLeft(attrs[end:], " \t")
+
+		value, found := strings.CutPrefix(attrs, "=")
+		if !found {
+			values[name] = ""
+			continue
+		}
+
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[:1]
+			value, attrs, _ = strings.Cut(value[1:], quote)
+		} else {
+			end := strings.IndexAny(value, " \t")
+			if end < 0 {
+				end = len(value)
+			}
+
+			value, attrs = value[:end], value[end:]
+		}
+
+		values[name] = value
+	}
+}
@@ -0,0 +1,141 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTaggedText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Span
+	}{
+		{
+			name: "plain",
+			text: "No tags",
+			want: []Span{{Text: "No tags"}},
+		},
+		{
+			name: "nested",
+			text: "<i>Hello <b>you</b></i>!",
+			want: []Span{
+				{Text: "Hello ", Italic: true},
+				{Text: "you", Italic: true, Bold: true},
+				{Text: "!"},
+			},
+		},
+		{
+			name: "font attributes",
+			text: `<font face="Times New Roman" color=#ff0000 size='20'>Red</font>`,
+			want: []Span{{Text: "Red", Color: "#FF0000", Font: "Times New Roman", Size: 20}},
+		},
+		{
+			name: "across lines",
+			text: "<I>First\nSecond</I>",
+			want: []Span{{Text: "First\nSecond", Italic: true}},
+		},
+		{
+			name: "unknown and unmatched tags",
+			text: "a <3 b</i> <x>c</x>",
+			want: []Span{{Text: "a <3 b</i> <x>c</x>"}},
+		},
+		{
+			name: "unclosed",
+			text: "<u>Underlined",
+			want: []Span{{Text: "Underlined", Underline: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTaggedText(tt.text, srtMarkup); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatTaggedText(t *testing.T) {
+	spans := []Span{
+		{Text: "Hello ", Italic: true},
+		{Text: "you", Italic: true, Bold: true},
+		{Text: "\n"},
+		{Text: "Red", Color: "#FF0000"},
+	}
+
+	want := "<i>Hello <b>you</b></i>\n<font color=\"#FF0000\">Red</font>"
+	if got := formatTaggedText(spans, srtMarkup); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	want = "<i>Hello <b>you</b></i>\n<c.red>Red</c>"
+	if got := formatTaggedText(spans, vttMarkup); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got := parseTaggedText(formatTaggedText(spans, srtMarkup), srtMarkup); !reflect.DeepEqual(got, spans) {
+		t.Errorf("expected %+v after a round trip, got %+v", spans, got)
+	}
+}
+
+func TestParseTaggedText_Vtt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Span
+	}{
+		{
+			name: "voice and class",
+			text: "<v.loud Mary>Hi <c.yellow.bg_blue>there</c>",
+			want: []Span{
+				{Text: "Hi ", Voice: "Mary"},
+				{Text: "there", Voice: "Mary", Color: "yellow"},
+			},
+		},
+		{
+			name: "ruby",
+			text: "<ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby>です",
+			want: []Span{
+				{Text: "漢", Ruby: "かん"},
+				{Text: "字", Ruby: "じ"},
+				{Text: "です"},
+			},
+		},
+		{
+			name: "entities, language and timestamps",
+			text: "<lang en>Fish &amp; chips</lang> &lt;3 <00:01.500>now",
+			want: []Span{{Text: "Fish & chips <3 now"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTaggedText(tt.text, vttMarkup); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatTaggedText_Ruby(t *testing.T) {
+	spans := []Span{
+		{Text: "漢", Ruby: "かん", Voice: "Aki"},
+		{Text: "字", Ruby: "じ", Voice: "Aki"},
+		{Text: " & more", Voice: "Aki"},
+	}
+
+	want := "<v Aki><ruby>漢<rt>かん</rt></ruby><ruby>字<rt>じ</rt></ruby> &amp; more</v>"
+	if got := formatTaggedText(spans, vttMarkup); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got := parseTaggedText(want, vttMarkup); !reflect.DeepEqual(got, spans) {
+		t.Errorf("expected %+v after a round trip, got %+v", spans, got)
+	}
+
+	want = "漢(かん)字(じ) & more"
+	if got := formatTaggedText(spans, srtMarkup); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
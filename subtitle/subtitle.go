@@ -9,102 +9,608 @@ import (
 	"strconv"
 	"strings"
 	"time"
-)
-
-const (
-	ntscRateNum = 24000
-	ntscRateDen = 1001
-	ntscRateDiv = 1000
+	"unicode"
 )
 
 type Subtitle struct {
 	Start time.Duration
 	End   time.Duration
 	Text  string
+	// Spans is the styled text when the input carries formatting, e.g.
+	// italics; Text holds the same text without it. It is kept behind a
+	// pointer so that cues stay comparable.
+	Spans *StyledText
+	// Vtt, Ass and Sami hold the properties of the cue specific to a format,
+	// which the other formats leave out
+	Vtt  VttCue
+	Ass  AssEvent
+	Sami SamiCue
+}
+
+// hidden reports whether the cue is an event that is not shown, e.g. an ASS
+// comment.
+func (s Subtitle) hidden() bool {
+	return s.Ass.Kind != ""
+}
+
+var (
+	ErrEmptyLine    = errors.New("empty line")
+	ErrMissingBrace = errors.New("missing brace")
+	ErrInvalidFrame = errors.New("invalid frame number")
+)
+
+// txtLine is a tokenized MicroDVD or MPL2 line.
+type txtLine struct {
+	startFrame int64
+	endFrame   int64
+	// openEnded marks {start}{} cues, displayed until the next one
+	openEnded bool
+	text      string
+}
+
+// txtDialect describes a line-based format of cues timed in frames or other
+// units, e.g. {100}{200}text in MicroDVD or [10][20]text in MPL2.
+type txtDialect struct {
+	// name of the format in read errors
+	name string
+	// open and close delimit the times
+	open, close string
+	// unit of the times in parse errors, e.g. "frame"
+	unit string
+	// rate converts the times, the frame rate of the input when zero
+	rate FrameRate
+	// parseText parses the markup of a cue into spans
+	parseText func(text string) []Span
+}
+
+var microDVD = txtDialect{
+	name:      "txt",
+	open:      "{",
+	close:     "}",
+	unit:      "frame",
+	parseText: parseTxtText,
 }
 
-func newSubtitleFromTxt(line string) (sub Subtitle, err error) {
-	// Parse format: {123}{164}text|text
-	// Assume input is correctly formatted
+// cutTxtFrame splits "{123}rest" into the trimmed frame value and the rest.
+func (d txtDialect) cutTxtFrame(
+	line, field string,
+) (value, rest string, err error) {
+	rest, found := strings.CutPrefix(line, d.open)
+	if !found {
+		return "", line, fmt.Errorf(
+			"%w: expected '%s' before %s",
+			ErrMissingBrace,
+			d.open,
+			field,
+		)
+	}
+
+	value, rest, found = strings.Cut(rest, d.close)
+	if !found {
+		return "", line, fmt.Errorf(
+			"%w: expected '%s' after %s",
+			ErrMissingBrace,
+			d.close,
+			field,
+		)
+	}
 
-	// Find positions of braces (adding +1 to skip past the braces)
-	startFrom := strings.Index(line, "{") + 1
-	startTill := strings.Index(line, "}")
-	endFrom := strings.Index(line[startTill:], "{") + startTill + 1
-	endTill := strings.Index(line[endFrom-1:], "}") + endFrom - 1
+	return strings.TrimSpace(value), rest, nil
+}
 
-	// Parse frame numbers
-	startFrame, err := strconv.ParseInt(line[startFrom:startTill], 10, 64)
+func parseTxtFrame(value, field string) (int64, error) {
+	// Limit frames to 32 bits, so that conversions cannot overflow
+	frame, err := strconv.ParseUint(value, 10, 32)
 	if err != nil {
-		return sub, fmt.Errorf("failed to parse start frame: %w", err)
+		return 0, &valueError{field: field, value: value, err: ErrInvalidFrame}
 	}
 
-	endFrame, err := strconv.ParseInt(line[endFrom:endTill], 10, 64)
+	return int64(frame), nil
+}
+
+func parseTxtLine(line string) (parsed txtLine, err error) {
+	return microDVD.parseLine(line)
+}
+
+func (d txtDialect) parseLine(line string) (parsed txtLine, err error) {
+	// Parse format: {123}{164}text|text or {123}{}text
+	line = strings.TrimRightFunc(line, unicode.IsSpace)
+	rest := strings.TrimLeft(strings.TrimPrefix(line, byteOrderMark), " \t")
+
+	if rest == "" {
+		return parsed, ErrEmptyLine
+	}
+
+	// rest is always a suffix of line, which gives the current column
+	column := func() int {
+		return len(line) - len(rest) + 1
+	}
+
+	from := column()
+	field := "start " + d.unit
+
+	start, rest, err := d.cutTxtFrame(rest, field)
 	if err != nil {
-		return sub, fmt.Errorf("failed to parse end frame: %w", err)
+		return parsed, &ParseError{Column: from, Text: line, Err: err}
 	}
 
-	// Extract text and convert | to newlines
-	text := strings.ReplaceAll(line[endTill+1:], "|", "\n")
+	if parsed.startFrame, err = parseTxtFrame(start, field); err != nil {
+		return parsed, &ParseError{Column: from + 1, Text: line, Err: err}
+	}
 
-	// Convert frames to duration using NTSC rate
-	// frame * (ntscRateDen * ntscRateDiv) / ntscRateNum = milliseconds
-	div := int64(ntscRateNum)
-	mul := int64(ntscRateDen * ntscRateDiv)
+	rest = strings.TrimLeft(rest, " \t")
+	from = column()
+	field = "end " + d.unit
 
-	sub.Start = time.Duration(startFrame*mul/div) * time.Millisecond
-	sub.End = time.Duration(endFrame*mul/div) * time.Millisecond
-	sub.Text = text
+	end, rest, err := d.cutTxtFrame(rest, field)
+	if err != nil {
+		return parsed, &ParseError{Column: from, Text: line, Err: err}
+	}
 
-	return sub, nil
+	parsed.text = rest
+
+	if end == "" {
+		parsed.openEnded = true
+		return parsed, nil
+	}
+
+	if parsed.endFrame, err = parseTxtFrame(end, field); err != nil {
+		return parsed, &ParseError{Column: from + 1, Text: line, Err: err}
+	}
+
+	return parsed, nil
 }
 
-func writeTxtDuration(w io.Writer, d time.Duration) error {
-	div := int64(ntscRateDen * ntscRateDiv)
-	frame := (d.Milliseconds()*ntscRateNum + div/2) / div
+func (d txtDialect) subtitle(l txtLine, rate FrameRate) Subtitle {
+	spans := d.parseText(l.text)
+
+	return Subtitle{
+		Start: rate.frameToDuration(l.startFrame),
+		End:   rate.frameToDuration(l.endFrame),
+		Text:  spansText(spans),
+		Spans: styledText(spans),
+	}
+}
+
+// applyTxtCode applies a MicroDVD control code, e.g. {y:i} or {c:$0000FF},
+// to the style. Codes without a Span counterpart are ignored.
+func applyTxtCode(style *Span, key byte, value string) {
+	switch key | 0x20 {
+	case 'y':
+		for flag := range strings.SplitSeq(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(flag)) {
+			case "i":
+				style.Italic = true
+			case "b":
+				style.Bold = true
+			case "u":
+				style.Underline = true
+			}
+		}
+	case 'c':
+		// Colours are written $BBGGRR
+		bgr := strings.TrimPrefix(strings.TrimSpace(value), "$")
+		_, err := strconv.ParseUint(bgr, 16, 32)
+		if err == nil && len(bgr) == 6 {
+			style.Color = strings.ToUpper("#" + bgr[4:] + bgr[2:4] + bgr[:2])
+		}
+	case 'f':
+		style.Font = strings.TrimSpace(value)
+	case 's':
+		if size, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			style.Size = size
+		}
+	}
+}
+
+// cutTxtCodes strips the control codes and the / italic prefix from the
+// start of a MicroDVD line, returning the styles of the line and, for
+// uppercase codes, of the whole cue.
+func cutTxtCodes(line string) (rest string, lineStyle, cueStyle Span) {
+	rest = line
+
+	for {
+		if after, ok := strings.CutPrefix(rest, "/"); ok {
+			lineStyle.Italic = true
+			rest = after
+
+			continue
+		}
+
+		end := strings.Index(rest, "}")
+		if end < 3 || rest[0] != '{' || rest[2] != ':' ||
+			rest[1]|0x20 < 'a' || rest[1]|0x20 > 'z' {
+			return rest, lineStyle, cueStyle
+		}
+
+		style := &lineStyle
+		if rest[1] < 'a' {
+			style = &cueStyle
+		}
+
+		applyTxtCode(style, rest[1], rest[3:end])
+		rest = rest[end+1:]
+	}
+}
+
+// overlay returns the style with the settings of top applied on it.
+func (s Span) overlay(top Span) Span {
+	s.Italic = s.Italic || top.Italic
+	s.Bold = s.Bold || top.Bold
+	s.Underline = s.Underline || top.Underline
+
+	if top.Color != "" {
+		s.Color = top.Color
+	}
+
+	if top.Font != "" {
+		s.Font = top.Font
+	}
+
+	if top.Size != 0 {
+		s.Size = top.Size
+	}
+
+	return s
+}
+
+// parseTxtText parses the |-separated lines of a MicroDVD cue into spans.
+// Lowercase control codes style their line, uppercase ones the whole cue.
+func parseTxtText(text string) []Span {
+	var (
+		lines     = strings.Split(text, "|")
+		lineStyle = make([]Span, len(lines))
+		cueStyle  Span
+	)
+
+	for i, line := range lines {
+		var cue Span
+
+		lines[i], lineStyle[i], cue = cutTxtCodes(line)
+		cueStyle = cueStyle.overlay(cue)
+	}
+
+	spans := make([]Span, 0, 2*len(lines))
+
+	for i, line := range lines {
+		if i > 0 {
+			span := cueStyle
+			span.Text = "\n"
+			spans = append(spans, span)
+		}
+
+		span := cueStyle.overlay(lineStyle[i])
+		span.Text = line
+		spans = append(spans, span)
+	}
+
+	return mergeSpans(spans)
+}
+
+// formatTxtCodes writes the control codes giving the style, uppercase for
+// cue-scoped codes. Colours other than "#RRGGBB" and WebVTT names cannot be
+// written.
+func formatTxtCodes(b *strings.Builder, style Span, cue bool) {
+	code := func(key byte, value string) {
+		if cue {
+			key &^= 0x20
+		}
+
+		fmt.Fprintf(b, "{%c:%s}", key, value)
+	}
+
+	var flags []string
+	if style.Italic {
+		flags = append(flags, "i")
+	}
+
+	if style.Bold {
+		flags = append(flags, "b")
+	}
+
+	if style.Underline {
+		flags = append(flags, "u")
+	}
+
+	if len(flags) > 0 {
+		code('y', strings.Join(flags, ","))
+	}
+
+	if rgb, ok := colorHex(style.Color); ok {
+		code('c', "$"+rgb[4:]+rgb[2:4]+rgb[:2])
+	}
+
+	if style.Font != "" {
+		code('f', style.Font)
+	}
+
+	if style.Size != 0 {
+		code('s', strconv.Itoa(style.Size))
+	}
+}
+
+// formatTxtText renders the text of the cue as |-separated MicroDVD lines.
+// Codes only style whole lines, so a line keeps the style shared by all its
+// spans, and the style shared by all lines is written once for the cue.
+func formatTxtText(sub Subtitle) string {
+	if sub.Spans == nil {
+		return strings.ReplaceAll(sub.Text, "\n", "|")
+	}
+
+	var (
+		b      strings.Builder
+		lines  = spanLines(flattenRuby(*sub.Spans))
+		styles = make([]Span, len(lines))
+		styled []Span
+	)
+
+	for i, line := range lines {
+		styles[i] = commonStyle(line)
+
+		if len(line) > 0 {
+			styled = append(styled, styles[i])
+		}
+	}
+
+	cueStyle := commonStyle(styled)
+	formatTxtCodes(&b, cueStyle, true)
+
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('|')
+		}
+
+		// Leave out what the cue codes already set
+		style := styles[i]
+		style.Italic = style.Italic && !cueStyle.Italic
+		style.Bold = style.Bold && !cueStyle.Bold
+		style.Underline = style.Underline && !cueStyle.Underline
+
+		if style.Color == cueStyle.Color {
+			style.Color = ""
+		}
+
+		if style.Font == cueStyle.Font {
+			style.Font = ""
+		}
+
+		if style.Size == cueStyle.Size {
+			style.Size = 0
+		}
+
+		formatTxtCodes(&b, style, false)
+		b.WriteString(spansText(line))
+	}
+
+	return b.String()
+}
+
+// frameRateHeader recognises the de-facto {1}{1}25.000 header line declaring
+// the frame rate of a MicroDVD file.
+func (l txtLine) frameRateHeader() (FrameRate, bool) {
+	if l.startFrame != 1 || l.endFrame != 1 || l.openEnded {
+		return FrameRate{}, false
+	}
+
+	rate, err := ParseFrameRate(l.text)
+
+	return rate, err == nil
+}
+
+func writeTxtDuration(w io.Writer, d time.Duration, rate FrameRate) error {
 	_, err := fmt.Fprintf(
 		w,
 		"{%d}",
-		frame,
+		rate.durationToFrame(d),
 	)
 
 	return err
 }
 
-func writeTxtSubtitle(w io.Writer, sub Subtitle) error {
+func writeTxtSubtitle(w io.Writer, sub Subtitle, rate FrameRate) error {
 	var err error
 
-	if err = writeTxtDuration(w, sub.Start); err != nil {
+	if err = writeTxtDuration(w, sub.Start, rate); err != nil {
 		return err
 	}
 
-	if err = writeTxtDuration(w, sub.End); err != nil {
+	if err = writeTxtDuration(w, sub.End, rate); err != nil {
 		return err
 	}
 
-	text := strings.ReplaceAll(sub.Text, "\n", "|")
-	_, err = fmt.Fprintln(w, text)
+	_, err = fmt.Fprintln(w, formatTxtText(sub))
 
 	return err
 }
 
-func writeSrtDuration(w io.Writer, d time.Duration) error {
+func newTxtPrinter(writer io.Writer, config PrinterConfig) Printer {
+	var rate FrameRate
+
+	return &printer{
+		writer: writer,
+		// The rate is settled with the header, once the document is read
+		header: func(w io.Writer, _ *Subtitle) error {
+			doc := config.document()
+
+			// A header read from MicroDVD input is kept, other documents
+			// only get one on request. Frames are only counted at the
+			// document rate when the header declares it.
+			header := config.FrameRateHeader ||
+				doc.Format == TxtFormat && !doc.FrameRate.IsZero()
+
+			rate = config.FrameRate
+			if rate.IsZero() && header {
+				rate = doc.FrameRate
+			}
+
+			rate = rate.orDefault()
+
+			if !header {
+				return nil
+			}
+
+			_, err := fmt.Fprintf(w, "%s%s\n", txtFrameRateHeader, rate)
+
+			return err
+		},
+		write: func(w io.Writer, sub Subtitle) error {
+			return writeTxtSubtitle(w, sub, rate)
+		},
+	}
+}
+
+func parseSrtDuration(value string) (time.Duration, error) {
+	// Parse format: HH:MM:SS,mmm (a dot is accepted instead of the comma)
+	clock, frac, _ := strings.Cut(strings.Replace(value, ".", ",", 1), ",")
+
+	parts := strings.Split(clock, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var d time.Duration
+
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+
+	for i, unit := range units {
+		n, err := strconv.ParseUint(parts[i], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q: %w", value, err)
+		}
+
+		d += time.Duration(n) * unit
+	}
+
+	if frac == "" {
+		return d, nil
+	}
+
+	if len(frac) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	// Pad short fractions, so that ",5" means 500 milliseconds
+	frac += strings.Repeat("0", 3-len(frac))
+
+	millis, err := strconv.ParseUint(frac, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %w", value, err)
+	}
+
+	return d + time.Duration(millis)*time.Millisecond, nil
+}
+
+// parseCueTiming parses a "start --> end [settings]" line using parse for
+// both timestamps.
+func parseCueTiming(
+	line string,
+	parse func(string) (time.Duration, error),
+) (start, end time.Duration, settings string, err error) {
+	from, till, found := strings.Cut(line, "-->")
+	if !found {
+		return 0, 0, "", &ParseError{
+			Column: 1,
+			Err:    errors.New("missing --> in timing line"),
+		}
+	}
+
+	value := strings.TrimSpace(from)
+	if start, err = parse(value); err != nil {
+		return 0, 0, "", &ParseError{
+			Column: len(from) - len(strings.TrimLeft(from, " \t")) + 1,
+			Err:    &valueError{field: "start time", value: value, err: err},
+		}
+	}
+
+	fields := strings.Fields(till)
+	if len(fields) == 0 {
+		return 0, 0, "", &ParseError{
+			Column: len(line) + 1,
+			Err:    errors.New("missing end time in timing line"),
+		}
+	}
+
+	if end, err = parse(fields[0]); err != nil {
+		return 0, 0, "", &ParseError{
+			Column: len(from) + len("-->") + strings.Index(till, fields[0]) + 1,
+			Err:    &valueError{field: "end time", value: fields[0], err: err},
+		}
+	}
+
+	return start, end, strings.Join(fields[1:], " "), nil
+}
+
+func newSubtitleFromSrt(
+	line string,
+	readLine func() (string, bool),
+) (sub Subtitle, err error) {
+	// Parse block:
+	// 1
+	// 00:00:05,120 --> 00:00:06,840
+	// text
+	// text
+	//
+	// The index is optional, as some files omit it.
+	if !strings.Contains(line, "-->") {
+		index := strings.TrimSpace(line)
+		if _, err = strconv.ParseUint(index, 10, 64); err != nil {
+			return sub, &valueError{field: "index", value: index, err: err}
+		}
+
+		var ok bool
+		if line, ok = readLine(); !ok {
+			return sub, errors.New("missing timing line after index")
+		}
+	}
+
+	// Ignore optional display coordinates following the end time
+	sub.Start, sub.End, _, err = parseCueTiming(line, parseSrtDuration)
+	if err != nil {
+		return sub, err
+	}
+
+	var text []string
+
+	for {
+		line, ok := readLine()
+		if !ok || strings.TrimSpace(line) == "" {
+			break
+		}
+
+		text = append(text, line)
+	}
+
+	spans := parseTaggedText(strings.Join(text, "\n"), srtMarkup)
+	sub.Text = spansText(spans)
+	sub.Spans = styledText(spans)
+
+	return sub, nil
+}
+
+// writeClockDuration writes HH:MM:SS followed by the separator and
+// milliseconds.
+func writeClockDuration(w io.Writer, d time.Duration, sep string) error {
 	hours := d / time.Hour
 	minutes := (d % time.Hour) / time.Minute
 	seconds := (d % time.Minute) / time.Second
 	millis := (d % time.Second) / time.Millisecond
 	_, err := fmt.Fprintf(
 		w,
-		"%02d:%02d:%02d,%03d",
+		"%02d:%02d:%02d%s%03d",
 		hours,
 		minutes,
 		seconds,
+		sep,
 		millis,
 	)
 
 	return err
 }
 
+func writeSrtDuration(w io.Writer, d time.Duration) error {
+	return writeClockDuration(w, d, ",")
+}
+
 func writeSrtSubtitle(w io.Writer, sub Subtitle, n int) error {
 	var err error
 
@@ -124,41 +630,103 @@ func writeSrtSubtitle(w io.Writer, sub Subtitle, n int) error {
 		return err
 	}
 
-	_, err = fmt.Fprintf(w, "\n%s\n\n", sub.Text)
+	text := formatTaggedText(sub.spans(), srtMarkup)
+	_, err = fmt.Fprintf(w, "\n%s\n\n", text)
 
 	return err
 }
 
-var ErrNotImplemented = errors.New("not implemented")
+func newSrtPrinter(writer io.Writer, _ PrinterConfig) Printer {
+	n := 0
 
-const readBufferSize = 256 * 1024
+	return &printer{
+		writer: writer,
+		write: func(w io.Writer, sub Subtitle) error {
+			n++
+			return writeSrtSubtitle(w, sub, n)
+		},
+	}
+}
 
-type FileFormat uint8
+var (
+	ErrNotImplemented = errors.New("not implemented")
+	ErrUnknownFormat  = errors.New("unknown format")
+)
 
 const (
-	UnknownFormat FileFormat = iota
-	TxtFormat
-	SrtFormat
+	readBufferSize     = 256 * 1024
+	byteOrderMark      = "\uFEFF"
+	txtFrameRateHeader = "{1}{1}"
+	// openEndedDuration is the default display time of cues without an end
+	// time, unless the next cue starts earlier
+	openEndedDuration = 3 * time.Second
 )
 
+// ReaderConfig holds options for reading subtitles. The zero value uses the
+// defaults.
+type ReaderConfig struct {
+	// Name identifies the input in parse errors, e.g. its path
+	Name string
+	// Lenient skips malformed cues instead of stopping at the first one,
+	// yielding a SkippedCuesError after the last cue
+	Lenient bool
+	// FrameRate of frame-based formats, overriding a MicroDVD frame rate
+	// header; the header rate, then DefaultFrameRate when zero
+	FrameRate FrameRate
+	// Document, when set, receives the file-level information of the input
+	Document *Document
+	// DisplayTime of cues without an end time, e.g. TMPlayer cues; 3 seconds
+	// when zero
+	DisplayTime time.Duration
+	// DisplayPolicy decides whether cues without an end time are shown for
+	// the display time or until the next cue
+	DisplayPolicy DisplayPolicy
+	// Track selects the track to read from files with several, e.g. a SAMI
+	// language class or its language; every track is read when empty
+	Track string
+	// SingleTrack reads only the first track when Track is empty, e.g. for
+	// outputs whose format cannot carry several
+	SingleTrack bool
+}
+
+// PrinterConfig holds options for writing subtitles. The zero value uses the
+// defaults.
+type PrinterConfig struct {
+	// FrameRate of frame-based formats; the Document frame rate when the
+	// MicroDVD header declares it, then DefaultFrameRate when zero
+	FrameRate FrameRate
+	// FrameRateHeader emits the {1}{1}fps header line in MicroDVD output,
+	// which is also written when the Document was read from MicroDVD with
+	// one
+	FrameRateHeader bool
+	// Document, when set, provides the file-level information written to
+	// the header, e.g. one filled by the reader of the input
+	Document *Document
+	// Language overrides the Document language, e.g. for inputs without
+	// one written to formats requiring it such as IMSC1
+	Language string
+}
+
+// NewSubtitlePrinter returns a Printer of the format, or nil when the format
+// cannot be written. The printer must be closed to finish the document.
 func NewSubtitlePrinter(
 	writer io.Writer,
 	format FileFormat,
-) func(sub Subtitle) error {
-	switch format {
-	case SrtFormat:
-		n := 0
-		return func(sub Subtitle) error {
-			n++
-			return writeSrtSubtitle(writer, sub, n)
-		}
-	case TxtFormat:
-		return func(sub Subtitle) error {
-			return writeTxtSubtitle(writer, sub)
-		}
-	default:
+) Printer {
+	return NewSubtitlePrinterWithConfig(writer, format, PrinterConfig{})
+}
+
+func NewSubtitlePrinterWithConfig(
+	writer io.Writer,
+	format FileFormat,
+	config PrinterConfig,
+) Printer {
+	info, ok := lookupFormat(format)
+	if !ok || info.Encoder == nil {
 		return nil
 	}
+
+	return info.Encoder.Encode(writer, config)
 }
 
 func newScannerPull(reader io.Reader) (
@@ -186,36 +754,248 @@ func newScannerPull(reader io.Reader) (
 func newTxtSubtitlesIter(
 	next func() (string, error, bool),
 	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return microDVD.subtitlesIter(next, stop, config)
+}
+
+func (d txtDialect) subtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
 ) iter.Seq2[Subtitle, error] {
 	return func(yield func(Subtitle, error) bool) {
 		defer stop()
 
+		var (
+			reader = &lineReader{next: next, name: config.Name}
+			errs   = &errorPolicy{lenient: config.Lenient}
+			doc    = config.document()
+			rate   = d.rate
+			// Only frame-based input may start with a frame rate header
+			first = rate.IsZero()
+			// pending is an open-ended cue waiting for the next start
+			pending *Subtitle
+		)
+
+		if rate.IsZero() {
+			rate = config.FrameRate.orDefault()
+		}
+
 		for {
-			line, err, ok := next()
+			line, ok := reader.readLine()
 			if !ok {
-				return
+				break
 			}
+
+			parsed, err := d.parseLine(line)
+			if errors.Is(err, ErrEmptyLine) {
+				continue
+			}
+
 			if err != nil {
-				yield(
-					Subtitle{},
-					fmt.Errorf("error reading txt subtitle: %w", err),
-				)
+				if parseErr := reader.parseError(err); !errs.skip(parseErr) {
+					yield(Subtitle{}, parseErr)
+					return
+				}
+
+				continue
+			}
+
+			if first {
+				first = false
+
+				// A configured rate overrides the header, as when writing
+				if header, ok := parsed.frameRateHeader(); ok {
+					if config.FrameRate.IsZero() {
+						rate = header
+					}
+
+					doc.FrameRate = header
+
+					continue
+				}
+			}
+
+			sub := d.subtitle(parsed, rate)
+
+			if pending != nil {
+				config.endOpenCue(pending, sub.Start, DisplayFixed)
+
+				if !yield(*pending, nil) {
+					return
+				}
+
+				pending = nil
+			}
+
+			if parsed.openEnded {
+				sub.End = sub.Start + config.displayTime()
+				pending = &sub
+				continue
+			}
+
+			if !yield(sub, nil) {
 				return
 			}
+		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading %s subtitle: %w", d.name, reader.err),
+			)
+			return
+		}
+
+		if pending != nil && !yield(*pending, nil) {
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+// lineReader wraps a scanner pull function, remembering read errors and
+// the position for parse errors, and stripping the byte order mark from the
+// first line.
+type lineReader struct {
+	next func() (string, error, bool)
+	err  error
+	// name of the source and the number and content of the last line
+	name string
+	line int
+	last string
+}
+
+func (r *lineReader) readLine() (string, bool) {
+	line, err, ok := r.next()
+	if !ok {
+		return "", false
+	}
+
+	if err != nil {
+		r.err = err
+		return "", false
+	}
+
+	if r.line == 0 {
+		line = strings.TrimPrefix(line, byteOrderMark)
+	}
+
+	r.line++
+	r.last = line
+
+	return line, true
+}
+
+// parseError places err at the last line read. Errors other than ParseError
+// point at the first column.
+func (r *lineReader) parseError(err error) *ParseError {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		parseErr = &ParseError{Column: 1, Err: err}
+	}
+
+	parseErr.Source = r.name
+	parseErr.Line = r.line
+
+	if parseErr.Text == "" {
+		parseErr.Text = r.last
+	}
+
+	return parseErr
+}
+
+// skipBlock consumes the remaining lines of the current block, up to and
+// including a blank line.
+func skipBlock(readLine func() (string, bool)) {
+	for {
+		line, ok := readLine()
+		if !ok || strings.TrimSpace(line) == "" {
+			return
+		}
+	}
+}
+
+// readBlock returns the remaining lines of the current block, consuming the
+// blank line after them.
+func readBlock(readLine func() (string, bool)) []string {
+	var lines []string
+
+	for {
+		line, ok := readLine()
+		if !ok || strings.TrimSpace(line) == "" {
+			return lines
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+// skipBlank returns the first non-blank line.
+func (r *lineReader) skipBlank() (string, bool) {
+	line, ok := r.readLine()
+	for ok && strings.TrimSpace(line) == "" {
+		line, ok = r.readLine()
+	}
+
+	return line, ok
+}
+
+func newSrtSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		defer stop()
+
+		reader := &lineReader{next: next, name: config.Name}
+		errs := &errorPolicy{lenient: config.Lenient}
+
+		for {
+			line, ok := reader.skipBlank()
+			if !ok {
+				break
+			}
+
+			sub, err := newSubtitleFromSrt(line, reader.readLine)
+			if reader.err != nil {
+				break
+			}
 
-			sub, err := newSubtitleFromTxt(line)
 			if err != nil {
-				yield(
-					Subtitle{},
-					fmt.Errorf("error parsing txt subtitle: %w", err),
-				)
-				return
+				if parseErr := reader.parseError(err); !errs.skip(parseErr) {
+					yield(Subtitle{}, parseErr)
+					return
+				}
+
+				// Resume at the next block
+				skipBlock(reader.readLine)
+
+				continue
 			}
 
-			if !yield(sub, err) {
+			if !yield(sub, nil) {
 				return
 			}
 		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading srt subtitle: %w", reader.err),
+			)
+
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
 	}
 }
 
@@ -223,17 +1003,24 @@ func NewSubtitlesIter(
 	reader io.Reader,
 	format FileFormat,
 ) iter.Seq2[Subtitle, error] {
-	next, stop := newScannerPull(reader)
-
-	switch format {
-	case TxtFormat:
-		return newTxtSubtitlesIter(next, stop)
+	return NewSubtitlesIterWithConfig(reader, format, ReaderConfig{})
+}
 
-	default:
+func NewSubtitlesIterWithConfig(
+	reader io.Reader,
+	format FileFormat,
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	info, ok := lookupFormat(format)
+	if !ok || info.Decoder == nil {
 		return func(yield func(Subtitle, error) bool) {
-			defer stop()
-
 			yield(Subtitle{}, ErrNotImplemented)
 		}
 	}
+
+	if config.Document != nil {
+		config.Document.Format = format
+	}
+
+	return info.Decoder.Decode(reader, config)
 }
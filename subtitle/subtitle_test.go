@@ -3,6 +3,9 @@ package subtitle
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -109,8 +112,8 @@ func TestNewSubtitlesIter_TxtFormat(t *testing.T) {
 					t.Errorf("subtitle %d: expected text %q, got %q", count, exp.text, sub.Text)
 				}
 
-				expectedStart := time.Duration(exp.startFrame*1001*1000/24000) * time.Millisecond
-				expectedEnd := time.Duration(exp.endFrame*1001*1000/24000) * time.Millisecond
+				expectedStart := time.Duration((exp.startFrame*1001*1000+12000)/24000) * time.Millisecond
+				expectedEnd := time.Duration((exp.endFrame*1001*1000+12000)/24000) * time.Millisecond
 
 				if sub.Start != expectedStart {
 					t.Errorf("subtitle %d: expected start %v, got %v", count, expectedStart, sub.Start)
@@ -150,22 +153,275 @@ func TestNewSubtitlesIter_UnknownFormat(t *testing.T) {
 }
 
 func TestNewSubtitlesIter_SrtFormat(t *testing.T) {
-	input := "1\n00:00:05,120 --> 00:00:06,840\nSome SRT subtitle"
-	reader := strings.NewReader(input)
+	tests := []struct {
+		name  string
+		input string
+		want  []Subtitle
+	}{
+		{
+			name:  "single subtitle",
+			input: "1\n00:00:05,120 --> 00:00:06,840\nSome SRT subtitle",
+			want: []Subtitle{
+				{
+					Start: 5*time.Second + 120*time.Millisecond,
+					End:   6*time.Second + 840*time.Millisecond,
+					Text:  "Some SRT subtitle",
+				},
+			},
+		},
+		{
+			name: "multiple subtitles with multiline text",
+			input: `1
+00:00:01,000 --> 00:00:02,000
+First line
+Second line
+
+2
+01:23:45,678 --> 01:23:50,123
+Third subtitle
+`,
+			want: []Subtitle{
+				{
+					Start: 1 * time.Second,
+					End:   2 * time.Second,
+					Text:  "First line\nSecond line",
+				},
+				{
+					Start: 1*time.Hour + 23*time.Minute + 45*time.Second + 678*time.Millisecond,
+					End:   1*time.Hour + 23*time.Minute + 50*time.Second + 123*time.Millisecond,
+					Text:  "Third subtitle",
+				},
+			},
+		},
+		{
+			name:  "byte order mark, CRLF and extra blank lines",
+			input: "\uFEFF1\r\n00:00:01,000 --> 00:00:02,000\r\nText\r\n\r\n\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nMore\r\n",
+			want: []Subtitle{
+				{Start: 1 * time.Second, End: 2 * time.Second, Text: "Text"},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "More"},
+			},
+		},
+		{
+			name:  "missing index and dot separator",
+			input: "00:00:01.500 --> 00:00:02.250\nNo index",
+			want: []Subtitle{
+				{
+					Start: 1*time.Second + 500*time.Millisecond,
+					End:   2*time.Second + 250*time.Millisecond,
+					Text:  "No index",
+				},
+			},
+		},
+		{
+			name:  "display coordinates after end time",
+			input: "1\n00:00:01,000 --> 00:00:02,000 X1:100 X2:200 Y1:10 Y2:20\nPositioned",
+			want: []Subtitle{
+				{Start: 1 * time.Second, End: 2 * time.Second, Text: "Positioned"},
+			},
+		},
+		{
+			name:  "empty text",
+			input: "1\n00:00:01,000 --> 00:00:02,000\n\n2\n00:00:03,000 --> 00:00:04,000\nText",
+			want: []Subtitle{
+				{Start: 1 * time.Second, End: 2 * time.Second, Text: ""},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "Text"},
+			},
+		},
+		{
+			name:  "empty input",
+			input: "",
+			want:  []Subtitle{},
+		},
+	}
 
-	iter := NewSubtitlesIter(reader, SrtFormat)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := strings.NewReader(tt.input)
+			iter := NewSubtitlesIter(reader, SrtFormat)
 
-	count := 0
-	for _, err := range iter {
-		count++
-		// SRT format is not implemented, should return ErrNotImplemented
-		if err != ErrNotImplemented {
-			t.Errorf("expected ErrNotImplemented for SrtFormat, got %v", err)
+			count := 0
+			for sub, err := range iter {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if count >= len(tt.want) {
+					t.Fatalf("got more subtitles than expected")
+				}
+
+				if sub != tt.want[count] {
+					t.Errorf("subtitle %d: expected %+v, got %+v", count, tt.want[count], sub)
+				}
+
+				count++
+			}
+
+			if count != len(tt.want) {
+				t.Errorf("expected %d subtitles, got %d", len(tt.want), count)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_SrtFormat_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantErr    string
+		wantLine   int
+		wantColumn int
+	}{
+		{
+			name:       "invalid index",
+			input:      "one\n00:00:01,000 --> 00:00:02,000\nText",
+			wantErr:    `invalid index "one"`,
+			wantLine:   1,
+			wantColumn: 1,
+		},
+		{
+			name:       "missing timing line",
+			input:      "1",
+			wantErr:    "missing timing line after index",
+			wantLine:   1,
+			wantColumn: 1,
+		},
+		{
+			name:       "missing arrow",
+			input:      "1\n00:00:01,000 00:00:02,000\nText",
+			wantErr:    "missing -->",
+			wantLine:   2,
+			wantColumn: 1,
+		},
+		{
+			name:       "invalid start time",
+			input:      "1\n00:0a:01,000 --> 00:00:02,000\nText",
+			wantErr:    `invalid start time "00:0a:01,000"`,
+			wantLine:   2,
+			wantColumn: 1,
+		},
+		{
+			name:       "invalid end time",
+			input:      "1\n00:00:01,000 -->  00:00:xx,000\nText",
+			wantErr:    `invalid end time "00:00:xx,000"`,
+			wantLine:   2,
+			wantColumn: 19,
+		},
+		{
+			name:       "missing end time",
+			input:      "1\n00:00:01,000 -->\nText",
+			wantErr:    "missing end time",
+			wantLine:   2,
+			wantColumn: 17,
+		},
+		{
+			name:       "error in a later block",
+			input:      "1\n00:00:01,000 --> 00:00:02,000\nText\n\n2\n  00:00:x3,000 --> 00:00:04,000\nText",
+			wantErr:    `invalid start time "00:00:x3,000"`,
+			wantLine:   6,
+			wantColumn: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ReaderConfig{Name: "movie.srt"}
+			iter := NewSubtitlesIterWithConfig(strings.NewReader(tt.input), SrtFormat, config)
+
+			count := 0
+			for _, err := range iter {
+				if err == nil {
+					continue
+				}
+
+				count++
+
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("expected ParseError, got %v", err)
+				}
+
+				if parseErr.Source != "movie.srt" || parseErr.Line != tt.wantLine ||
+					parseErr.Column != tt.wantColumn {
+					t.Errorf("expected position movie.srt:%d:%d, got %s:%d:%d",
+						tt.wantLine, tt.wantColumn, parseErr.Source, parseErr.Line, parseErr.Column)
+				}
+
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected %q error, got: %v", tt.wantErr, err)
+				}
+			}
+
+			if count != 1 {
+				t.Errorf("expected iterator to yield error once, got %d", count)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_SrtToTxt(t *testing.T) {
+	input := "1\n00:00:04,171 --> 00:00:06,840\nFirst line\nSecond line\n"
+
+	var buf bytes.Buffer
+	printer := NewSubtitlePrinter(&buf, TxtFormat)
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), SrtFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
 	}
 
-	if count != 1 {
-		t.Errorf("expected iterator to yield error once, got %d", count)
+	expected := "{100}{164}First line|Second line\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestNewSubtitlesIter_TxtToSrtToTxt(t *testing.T) {
+	for _, rate := range []FrameRate{{24000, 1001}, {25, 1}, {30000, 1001}, {60, 1}} {
+		t.Run(rate.String(), func(t *testing.T) {
+			// TestFrameRate_MillisecondRoundTrip covers every frame, this
+			// samples the first 3 hours through the printers and readers
+			var input strings.Builder
+			for frame := int64(0); frame < rate.Frame(3*time.Hour, RoundUp); frame += 13 {
+				fmt.Fprintf(&input, "{%d}{%d}Text\n", frame, frame+1)
+			}
+
+			convert := func(input string, from, to FileFormat) string {
+				var buf bytes.Buffer
+
+				printer := NewSubtitlePrinterWithConfig(&buf, to, PrinterConfig{FrameRate: rate})
+				subs := NewSubtitlesIterWithConfig(
+					strings.NewReader(input),
+					from,
+					ReaderConfig{FrameRate: rate},
+				)
+
+				for sub, err := range subs {
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					if err := printer.Write(sub); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				}
+
+				if err := printer.Close(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return buf.String()
+			}
+
+			srt := convert(input.String(), TxtFormat, SrtFormat)
+			if got := convert(srt, SrtFormat, TxtFormat); got != input.String() {
+				t.Error("expected the frame numbers to survive the round trip")
+			}
+		})
 	}
 }
 
@@ -214,8 +470,8 @@ func TestNewSubtitleFromTxt_LargeFrameNumbers(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		expectedStart := time.Duration(100000*1001*1000/24000) * time.Millisecond
-		expectedEnd := time.Duration(200000*1001*1000/24000) * time.Millisecond
+		expectedStart := time.Duration((100000*1001*1000+12000)/24000) * time.Millisecond
+		expectedEnd := time.Duration((200000*1001*1000+12000)/24000) * time.Millisecond
 
 		if sub.Start != expectedStart {
 			t.Errorf("expected start %v, got %v", expectedStart, sub.Start)
@@ -250,8 +506,8 @@ func TestNewSubtitlesIter_TxtFormat_EmptyText(t *testing.T) {
 			t.Errorf("expected empty text, got '%s'", sub.Text)
 		}
 
-		expectedStart := time.Duration(100*1001*1000/24000) * time.Millisecond
-		expectedEnd := time.Duration(200*1001*1000/24000) * time.Millisecond
+		expectedStart := time.Duration((100*1001*1000+12000)/24000) * time.Millisecond
+		expectedEnd := time.Duration((200*1001*1000+12000)/24000) * time.Millisecond
 
 		if sub.Start != expectedStart {
 			t.Errorf("expected start %v, got %v", expectedStart, sub.Start)
@@ -300,8 +556,8 @@ func TestNewSubtitlesIter_TxtFormat_InvalidStartFrame(t *testing.T) {
 			t.Fatal("expected error for invalid start frame, got nil")
 		}
 
-		if !strings.Contains(err.Error(), "failed to parse start frame") {
-			t.Errorf("expected 'failed to parse start frame' error, got: %v", err)
+		if err.Error() != `1:2: invalid start frame "abc"` {
+			t.Errorf("expected 'invalid start frame' error, got: %v", err)
 		}
 	}
 
@@ -324,8 +580,8 @@ func TestNewSubtitlesIter_TxtFormat_InvalidEndFrame(t *testing.T) {
 			t.Fatal("expected error for invalid end frame, got nil")
 		}
 
-		if !strings.Contains(err.Error(), "failed to parse end frame") {
-			t.Errorf("expected 'failed to parse end frame' error, got: %v", err)
+		if err.Error() != `1:7: invalid end frame "xyz"` {
+			t.Errorf("expected 'invalid end frame' error, got: %v", err)
 		}
 	}
 
@@ -400,8 +656,9 @@ func TestNewSubtitlesIter_TxtFormat_ParseErrorAfterValidSubtitle(t *testing.T) {
 
 		if err != nil {
 			gotError = true
-			if !strings.Contains(err.Error(), "error parsing txt subtitle") {
-				t.Errorf("expected 'error parsing txt subtitle' error, got: %v", err)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+				t.Errorf("expected ParseError on line 2, got: %v", err)
 			}
 			break
 		}
@@ -477,7 +734,7 @@ func TestNewSubtitlePrinter_SrtFormat(t *testing.T) {
 				t.Fatal("expected printer function, got nil")
 			}
 
-			err := printer(tt.subtitle)
+			err := printer.Write(tt.subtitle)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -517,7 +774,7 @@ func TestNewSubtitlePrinter_SrtFormat_MultipleSubtitles(t *testing.T) {
 	}
 
 	for _, sub := range subtitles {
-		if err := printer(sub); err != nil {
+		if err := printer.Write(sub); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	}
@@ -541,8 +798,8 @@ func TestNewSubtitlePrinter_TxtFormat(t *testing.T) {
 		{
 			name: "simple subtitle",
 			subtitle: Subtitle{
-				Start: time.Duration(100*1001*1000/24000) * time.Millisecond,
-				End:   time.Duration(200*1001*1000/24000) * time.Millisecond,
+				Start: time.Duration((100*1001*1000+12000)/24000) * time.Millisecond,
+				End:   time.Duration((200*1001*1000+12000)/24000) * time.Millisecond,
 				Text:  "Hello, World!",
 			},
 			want: "{100}{200}Hello, World!\n",
@@ -550,8 +807,8 @@ func TestNewSubtitlePrinter_TxtFormat(t *testing.T) {
 		{
 			name: "subtitle with multiline text",
 			subtitle: Subtitle{
-				Start: time.Duration(500*1001*1000/24000) * time.Millisecond,
-				End:   time.Duration(600*1001*1000/24000) * time.Millisecond,
+				Start: time.Duration((500*1001*1000+12000)/24000) * time.Millisecond,
+				End:   time.Duration((600*1001*1000+12000)/24000) * time.Millisecond,
 				Text:  "First line\nSecond line\nThird line",
 			},
 			want: "{500}{600}First line|Second line|Third line\n",
@@ -560,7 +817,7 @@ func TestNewSubtitlePrinter_TxtFormat(t *testing.T) {
 			name: "subtitle at zero frame",
 			subtitle: Subtitle{
 				Start: 0,
-				End:   time.Duration(50*1001*1000/24000) * time.Millisecond,
+				End:   time.Duration((50*1001*1000+12000)/24000) * time.Millisecond,
 				Text:  "Opening subtitle",
 			},
 			want: "{0}{50}Opening subtitle\n",
@@ -568,8 +825,8 @@ func TestNewSubtitlePrinter_TxtFormat(t *testing.T) {
 		{
 			name: "subtitle with empty text",
 			subtitle: Subtitle{
-				Start: time.Duration(100*1001*1000/24000) * time.Millisecond,
-				End:   time.Duration(150*1001*1000/24000) * time.Millisecond,
+				Start: time.Duration((100*1001*1000+12000)/24000) * time.Millisecond,
+				End:   time.Duration((150*1001*1000+12000)/24000) * time.Millisecond,
 				Text:  "",
 			},
 			want: "{100}{150}\n",
@@ -577,8 +834,8 @@ func TestNewSubtitlePrinter_TxtFormat(t *testing.T) {
 		{
 			name: "subtitle with large frame numbers",
 			subtitle: Subtitle{
-				Start: time.Duration(100000*1001*1000/24000) * time.Millisecond,
-				End:   time.Duration(200000*1001*1000/24000) * time.Millisecond,
+				Start: time.Duration((100000*1001*1000+12000)/24000) * time.Millisecond,
+				End:   time.Duration((200000*1001*1000+12000)/24000) * time.Millisecond,
 				Text:  "Long movie subtitle",
 			},
 			want: "{100000}{200000}Long movie subtitle\n",
@@ -594,7 +851,7 @@ func TestNewSubtitlePrinter_TxtFormat(t *testing.T) {
 				t.Fatal("expected printer function, got nil")
 			}
 
-			err := printer(tt.subtitle)
+			err := printer.Write(tt.subtitle)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -617,24 +874,24 @@ func TestNewSubtitlePrinter_TxtFormat_MultipleSubtitles(t *testing.T) {
 
 	subtitles := []Subtitle{
 		{
-			Start: time.Duration(100*1001*1000/24000) * time.Millisecond,
-			End:   time.Duration(150*1001*1000/24000) * time.Millisecond,
+			Start: time.Duration((100*1001*1000+12000)/24000) * time.Millisecond,
+			End:   time.Duration((150*1001*1000+12000)/24000) * time.Millisecond,
 			Text:  "First subtitle",
 		},
 		{
-			Start: time.Duration(200*1001*1000/24000) * time.Millisecond,
-			End:   time.Duration(250*1001*1000/24000) * time.Millisecond,
+			Start: time.Duration((200*1001*1000+12000)/24000) * time.Millisecond,
+			End:   time.Duration((250*1001*1000+12000)/24000) * time.Millisecond,
 			Text:  "Second subtitle",
 		},
 		{
-			Start: time.Duration(300*1001*1000/24000) * time.Millisecond,
-			End:   time.Duration(350*1001*1000/24000) * time.Millisecond,
+			Start: time.Duration((300*1001*1000+12000)/24000) * time.Millisecond,
+			End:   time.Duration((350*1001*1000+12000)/24000) * time.Millisecond,
 			Text:  "Third subtitle",
 		},
 	}
 
 	for _, sub := range subtitles {
-		if err := printer(sub); err != nil {
+		if err := printer.Write(sub); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	}
@@ -651,8 +908,8 @@ func TestNewSubtitlePrinter_TxtFormat_MultipleSubtitles(t *testing.T) {
 
 func TestNewSubtitlePrinter_TxtFormat_WriteErrors(t *testing.T) {
 	sub := Subtitle{
-		Start: time.Duration(100*1001*1000/24000) * time.Millisecond,
-		End:   time.Duration(200*1001*1000/24000) * time.Millisecond,
+		Start: time.Duration((100*1001*1000+12000)/24000) * time.Millisecond,
+		End:   time.Duration((200*1001*1000+12000)/24000) * time.Millisecond,
 		Text:  "Test subtitle",
 	}
 
@@ -683,7 +940,7 @@ func TestNewSubtitlePrinter_TxtFormat_WriteErrors(t *testing.T) {
 				t.Fatal("expected printer function, got nil")
 			}
 
-			err := printer(sub)
+			err := printer.Write(sub)
 			if err == nil {
 				t.Error("expected error from writer, got nil")
 			}
@@ -760,7 +1017,7 @@ func TestNewSubtitlePrinter_SrtFormat_WriteErrors(t *testing.T) {
 				t.Fatal("expected printer function, got nil")
 			}
 
-			err := printer(sub)
+			err := printer.Write(sub)
 			if err == nil {
 				t.Error("expected error from writer, got nil")
 			}
@@ -771,3 +1028,723 @@ func TestNewSubtitlePrinter_SrtFormat_WriteErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSubtitlesIterWithConfig_TxtFormat_FrameRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		frameRate FrameRate
+		wantStart time.Duration
+		wantEnd   time.Duration
+	}{
+		{
+			name:      "default NTSC film rate",
+			input:     "{25}{50}Text",
+			wantStart: 1043 * time.Millisecond,
+			wantEnd:   2085 * time.Millisecond,
+		},
+		{
+			name:      "PAL rate",
+			input:     "{25}{50}Text",
+			frameRate: FrameRate{25, 1},
+			wantStart: 1 * time.Second,
+			wantEnd:   2 * time.Second,
+		},
+		{
+			name:      "60 fps rate",
+			input:     "{60}{150}Text",
+			frameRate: FrameRate{60, 1},
+			wantStart: 1 * time.Second,
+			wantEnd:   2500 * time.Millisecond,
+		},
+		{
+			name:      "header rate",
+			input:     "{1}{1}25.000\n{25}{50}Text",
+			wantStart: 1 * time.Second,
+			wantEnd:   2 * time.Second,
+		},
+		{
+			name:      "configured rate overrides the header",
+			input:     "{1}{1}25.000\n{60}{150}Text",
+			frameRate: FrameRate{60, 1},
+			wantStart: 1 * time.Second,
+			wantEnd:   2500 * time.Millisecond,
+		},
+		{
+			name:      "header with NTSC rate",
+			input:     "{1}{1}29.97\n{30}{60}Text",
+			wantStart: 1001 * time.Millisecond,
+			wantEnd:   2002 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ReaderConfig{FrameRate: tt.frameRate}
+			iter := NewSubtitlesIterWithConfig(strings.NewReader(tt.input), TxtFormat, config)
+
+			count := 0
+			for sub, err := range iter {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				count++
+
+				if sub.Start != tt.wantStart || sub.End != tt.wantEnd || sub.Text != "Text" {
+					t.Errorf("expected %v-%v Text, got %+v", tt.wantStart, tt.wantEnd, sub)
+				}
+			}
+
+			if count != 1 {
+				t.Errorf("expected 1 subtitle, got %d", count)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_TxtFormat_HeaderLikeCueAfterFirstLine(t *testing.T) {
+	input := "{0}{25}First\n{1}{1}25.000"
+
+	count := 0
+	for _, err := range NewSubtitlesIter(strings.NewReader(input), TxtFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 subtitles, got %d", count)
+	}
+}
+
+func TestNewSubtitlePrinterWithConfig_TxtFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PrinterConfig
+		want   string
+	}{
+		{
+			name: "default rate without header",
+			want: "{24}{48}Text\n",
+		},
+		{
+			name:   "PAL rate",
+			config: PrinterConfig{FrameRate: FrameRate{25, 1}},
+			want:   "{25}{50}Text\n",
+		},
+		{
+			name:   "PAL rate with header",
+			config: PrinterConfig{FrameRate: FrameRate{25, 1}, FrameRateHeader: true},
+			want:   "{1}{1}25.000\n{25}{50}Text\n",
+		},
+		{
+			name:   "default rate with header",
+			config: PrinterConfig{FrameRateHeader: true},
+			want:   "{1}{1}23.976\n{24}{48}Text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printer := NewSubtitlePrinterWithConfig(&buf, TxtFormat, tt.config)
+
+			sub := Subtitle{Start: time.Second, End: 2 * time.Second, Text: "Text"}
+			if err := printer.Write(sub); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFileFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want FileFormat
+	}{
+		{"txt", TxtFormat},
+		{"microdvd", TxtFormat},
+		{"srt", SrtFormat},
+		{"SubRip", SrtFormat},
+		{"vtt", VttFormat},
+		{"webvtt", VttFormat},
+		{"ass", AssFormat},
+		{" ssa ", AssFormat},
+		{"mpl2", Mpl2Format},
+		{"TMPlayer", TmplayerFormat},
+		{"vplayer", VplayerFormat},
+		{"SubViewer", SubviewerFormat},
+		{"sami", SamiFormat},
+		{"smi", SamiFormat},
+		{"ttml", TtmlFormat},
+		{"DFXP", TtmlFormat},
+		{"imsc1", ImscFormat},
+		{"IMSC", ImscFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFileFormat(tt.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFileFormat_Errors(t *testing.T) {
+	for _, name := range []string{"", "unknown", "pdf"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseFileFormat(name)
+			if !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("expected ErrUnknownFormat, got %v", err)
+			}
+
+			if got != UnknownFormat {
+				t.Errorf("expected UnknownFormat, got %v", got)
+			}
+
+			if !strings.Contains(err.Error(), "txt, srt, vtt, ass") {
+				t.Errorf("expected supported formats in error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileFormat_String(t *testing.T) {
+	for _, format := range []FileFormat{TxtFormat, SrtFormat, VttFormat, AssFormat} {
+		got, err := ParseFileFormat(format.String())
+		if err != nil || got != format {
+			t.Errorf("%v: expected round trip, got %v, %v", format, got, err)
+		}
+	}
+
+	if got := FileFormat(200).String(); got != "FileFormat(200)" {
+		t.Errorf("expected FileFormat(200), got %q", got)
+	}
+}
+
+func TestParseTxtLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want txtLine
+	}{
+		{
+			name: "regular line",
+			line: "{123}{164}Hello|World",
+			want: txtLine{startFrame: 123, endFrame: 164, text: "Hello|World"},
+		},
+		{
+			name: "byte order mark",
+			line: "\uFEFF{1}{2}Text",
+			want: txtLine{startFrame: 1, endFrame: 2, text: "Text"},
+		},
+		{
+			name: "surrounding whitespace",
+			line: "  { 1 } {2}Text \t",
+			want: txtLine{startFrame: 1, endFrame: 2, text: "Text"},
+		},
+		{
+			name: "open ended",
+			line: "{100}{}Text",
+			want: txtLine{startFrame: 100, openEnded: true, text: "Text"},
+		},
+		{
+			name: "braces in text",
+			line: "{1}{2}{y:i}Text",
+			want: txtLine{startFrame: 1, endFrame: 2, text: "{y:i}Text"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTxtLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseTxtLine_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr error
+	}{
+		{"empty line", "", ErrEmptyLine},
+		{"whitespace only", " \t ", ErrEmptyLine},
+		{"byte order mark only", "\uFEFF", ErrEmptyLine},
+		{"no braces", "Hello World", ErrMissingBrace},
+		{"unterminated start", "{123", ErrMissingBrace},
+		{"missing end frame", "{123}Text", ErrMissingBrace},
+		{"unterminated end", "{123}{456", ErrMissingBrace},
+		{"closing brace first", "}123{{456}Text", ErrMissingBrace},
+		{"empty start", "{}{456}Text", ErrInvalidFrame},
+		{"negative start", "{-1}{456}Text", ErrInvalidFrame},
+		{"non-numeric end", "{1}{12a}Text", ErrInvalidFrame},
+		{"overflowing frame", "{99999999999999999999}{1}Text", ErrInvalidFrame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTxtLine(tt.line)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseTxtText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Span
+	}{
+		{
+			name: "line-scoped codes",
+			text: "{y:i}First|{y:b}{c:$0000FF}Second",
+			want: []Span{
+				{Text: "First", Italic: true},
+				{Text: "\n"},
+				{Text: "Second", Bold: true, Color: "#FF0000"},
+			},
+		},
+		{
+			name: "cue-scoped codes",
+			text: "{Y:i}{F:Arial}{S:20}First|Second",
+			want: []Span{{Text: "First\nSecond", Italic: true, Font: "Arial", Size: 20}},
+		},
+		{
+			name: "slash italic",
+			text: "/First|Second",
+			want: []Span{{Text: "First", Italic: true}, {Text: "\nSecond"}},
+		},
+		{
+			name: "line on top of cue",
+			text: "{Y:b}{y:i,u}First|Second",
+			want: []Span{
+				{Text: "First", Italic: true, Bold: true, Underline: true},
+				{Text: "\nSecond", Bold: true},
+			},
+		},
+		{
+			name: "unknown codes are dropped",
+			text: "{P:0,0}{H:UTF-8}Text",
+			want: []Span{{Text: "Text"}},
+		},
+		{
+			name: "codes only at the start",
+			text: "Text {y:i}",
+			want: []Span{{Text: "Text {y:i}"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTxtText(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatTxtText(t *testing.T) {
+	tests := []struct {
+		name  string
+		spans StyledText
+		want  string
+	}{
+		{
+			name: "line-scoped",
+			spans: []Span{
+				{Text: "First", Italic: true},
+				{Text: "\n"},
+				{Text: "Second", Bold: true, Color: "#FF0000"},
+			},
+			want: "{y:i}First|{y:b}{c:$0000FF}Second",
+		},
+		{
+			name:  "cue-scoped",
+			spans: []Span{{Text: "First\nSecond", Italic: true, Font: "Arial"}},
+			want:  "{Y:i}{F:Arial}First|Second",
+		},
+		{
+			name: "mixed line keeps the shared style",
+			spans: []Span{
+				{Text: "Hello ", Italic: true},
+				{Text: "you", Italic: true, Bold: true},
+				{Text: "\nBye", Italic: true},
+			},
+			want: "{Y:i}Hello you|Bye",
+		},
+		{
+			name:  "colour names",
+			spans: []Span{{Text: "Red", Color: "red"}, {Text: "\nOrange", Color: "orange"}},
+			want:  "{c:$0000FF}Red|Orange",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subtitle{Text: spansText(tt.spans), Spans: &tt.spans}
+			if got := formatTxtText(sub); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_TxtToSrtFormatting(t *testing.T) {
+	txt := "{100}{200}{y:i}Italic|{c:$0000FF}Red\n" +
+		"{300}{400}{Y:b}Bold|Lines\n" +
+		"{500}{600}Plain\n"
+	srt := "1\n00:00:04,171 --> 00:00:08,342\n<i>Italic</i>\n<font color=\"#FF0000\">Red</font>\n\n" +
+		"2\n00:00:12,513 --> 00:00:16,683\n<b>Bold\nLines</b>\n\n" +
+		"3\n00:00:20,854 --> 00:00:25,025\nPlain\n\n"
+
+	convert := func(input string, from, to FileFormat) string {
+		var buf bytes.Buffer
+
+		printer := NewSubtitlePrinter(&buf, to)
+
+		for sub, err := range NewSubtitlesIter(strings.NewReader(input), from) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := printer.Write(sub); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		return buf.String()
+	}
+
+	if got := convert(txt, TxtFormat, SrtFormat); got != srt {
+		t.Errorf("expected %q, got %q", srt, got)
+	}
+
+	if got := convert(srt, SrtFormat, TxtFormat); got != txt {
+		t.Errorf("expected %q, got %q", txt, got)
+	}
+}
+
+func TestNewSubtitlesIter_TxtFormat_ParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  ParseError
+	}{
+		{
+			name:  "invalid start frame",
+			input: "{1}{2}A\n{12a}{4}B\n",
+			want: ParseError{
+				Source: "movie.txt",
+				Line:   2,
+				Column: 2,
+				Text:   "{12a}{4}B",
+			},
+		},
+		{
+			name:  "invalid end frame after whitespace and blank lines",
+			input: "{1}{2}A\n\n  {3} {4x}B\n",
+			want: ParseError{
+				Source: "movie.txt",
+				Line:   3,
+				Column: 8,
+				Text:   "  {3} {4x}B",
+			},
+		},
+		{
+			name:  "missing brace",
+			input: "{1}{2}A\n{3}B\n",
+			want: ParseError{
+				Source: "movie.txt",
+				Line:   2,
+				Column: 4,
+				Text:   "{3}B",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ReaderConfig{Name: "movie.txt"}
+
+			for _, err := range NewSubtitlesIterWithConfig(strings.NewReader(tt.input), TxtFormat, config) {
+				if err == nil {
+					continue
+				}
+
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("expected ParseError, got %v", err)
+				}
+
+				got := *parseErr
+				got.Err = nil
+
+				if got != tt.want {
+					t.Errorf("expected %+v, got %+v", tt.want, got)
+				}
+
+				return
+			}
+
+			t.Error("expected an error")
+		})
+	}
+}
+
+func TestParseError_Error(t *testing.T) {
+	err := &ParseError{
+		Source: "movie.txt",
+		Line:   1532,
+		Column: 2,
+		Text:   "{12a}{1600}Text",
+		Err:    &valueError{field: "start frame", value: "12a", err: ErrInvalidFrame},
+	}
+
+	if got := err.Error(); got != `movie.txt:1532:2: invalid start frame "12a"` {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	if !errors.Is(err, ErrInvalidFrame) {
+		t.Error("expected ParseError to match its cause")
+	}
+
+	err.Source = ""
+	if got := err.Error(); got != `1532:2: invalid start frame "12a"` {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestNewSubtitlesIter_TxtFormat_MalformedInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantCount int
+		wantErr   error
+	}{
+		{"trailing newlines", "{1}{2}Text\n\n\n", 1, nil},
+		{"blank lines between cues", "{1}{2}A\n\n  \n{3}{4}B\n", 2, nil},
+		{"CRLF line endings", "{1}{2}A\r\n{3}{4}B\r\n", 2, nil},
+		{"line without braces", "{1}{2}A\nHello\n", 1, ErrMissingBrace},
+		{"single brace", "{1}{2}A\n{\n", 1, ErrMissingBrace},
+		{"invalid frame", "{1}{2}A\n{x}{4}B\n", 1, ErrInvalidFrame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count := 0
+
+			var gotErr error
+
+			for _, err := range NewSubtitlesIter(strings.NewReader(tt.input), TxtFormat) {
+				if err != nil {
+					gotErr = err
+					break
+				}
+
+				count++
+			}
+
+			if count != tt.wantCount {
+				t.Errorf("expected %d subtitles, got %d", tt.wantCount, count)
+			}
+
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, gotErr)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_TxtFormat_OpenEnded(t *testing.T) {
+	rate := FrameRate{25, 1}
+	input := "{25}{}Until next\n{50}{75}Regular\n{100}{}Capped\n{1000}{1025}Far\n{2000}{}Last\n"
+
+	want := []Subtitle{
+		{Start: 1 * time.Second, End: 2 * time.Second, Text: "Until next"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "Regular"},
+		{Start: 4 * time.Second, End: 4*time.Second + openEndedDuration, Text: "Capped"},
+		{Start: 40 * time.Second, End: 41 * time.Second, Text: "Far"},
+		{Start: 80 * time.Second, End: 80*time.Second + openEndedDuration, Text: "Last"},
+	}
+
+	iter := NewSubtitlesIterWithConfig(strings.NewReader(input), TxtFormat, ReaderConfig{FrameRate: rate})
+
+	count := 0
+	for sub, err := range iter {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if count >= len(want) {
+			t.Fatalf("got more subtitles than expected")
+		}
+
+		if sub != want[count] {
+			t.Errorf("subtitle %d: expected %+v, got %+v", count, want[count], sub)
+		}
+
+		count++
+	}
+
+	if count != len(want) {
+		t.Errorf("expected %d subtitles, got %d", len(want), count)
+	}
+}
+
+func FuzzNewSubtitlesIter_TxtFormat(f *testing.F) {
+	for _, seed := range []string{
+		"{123}{164}Hello|World",
+		"{1}{1}25.000\n{25}{}Open\n{50}{75}Text\n",
+		"\uFEFF{1}{2}Text\r\n\r\n",
+		"{",
+		"}{",
+		"{1}",
+		"{1}{",
+		"{}{}",
+		"{-1}{x}",
+		"{9223372036854775807}{4294967295}",
+		"\n\n\n",
+		"Hello",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for sub, err := range NewSubtitlesIter(strings.NewReader(input), TxtFormat) {
+			if err != nil {
+				if !errors.Is(err, ErrMissingBrace) && !errors.Is(err, ErrInvalidFrame) {
+					t.Fatalf("unexpected error type: %v", err)
+				}
+
+				continue
+			}
+
+			if sub.Start < 0 || sub.End < 0 {
+				t.Fatalf("negative timing in %+v", sub)
+			}
+		}
+	})
+}
+
+func TestNewSubtitlesIterWithConfig_Lenient(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		format    FileFormat
+		wantTexts []string
+		wantLines []int
+	}{
+		{
+			name:      "txt skips bad lines",
+			input:     "{1}{2}First\n{12a}{30}Broken\n{48}{72}Second\n{96}x\n",
+			format:    TxtFormat,
+			wantTexts: []string{"First", "Second"},
+			wantLines: []int{2, 4},
+		},
+		{
+			name: "srt skips bad blocks",
+			input: "1\n00:00:01,000 --> 00:00:02,000\nFirst\n\n" +
+				"2\n00:00:xx,000 --> 00:00:04,000\nBroken\nStill broken\n\n" +
+				"x\n00:00:05,000 --> 00:00:06,000\nBad index\n\n" +
+				"4\n00:00:07,000 --> 00:00:08,000\nSecond\n",
+			format:    SrtFormat,
+			wantTexts: []string{"First", "Second"},
+			wantLines: []int{6, 10},
+		},
+		{
+			name:      "no errors",
+			input:     "{1}{2}First\n",
+			format:    TxtFormat,
+			wantTexts: []string{"First"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter := NewSubtitlesIterWithConfig(
+				strings.NewReader(tt.input),
+				tt.format,
+				ReaderConfig{Lenient: true},
+			)
+
+			var (
+				texts   []string
+				skipped *SkippedCuesError
+			)
+
+			for sub, err := range iter {
+				if err != nil {
+					if skipped != nil || !errors.As(err, &skipped) {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					continue
+				}
+
+				if skipped != nil {
+					t.Fatal("expected SkippedCuesError to be yielded last")
+				}
+
+				texts = append(texts, sub.Text)
+			}
+
+			if !slices.Equal(texts, tt.wantTexts) {
+				t.Errorf("expected texts %q, got %q", tt.wantTexts, texts)
+			}
+
+			var lines []int
+			if skipped != nil {
+				for _, parseErr := range skipped.Errors {
+					lines = append(lines, parseErr.Line)
+				}
+			}
+
+			if !slices.Equal(lines, tt.wantLines) {
+				t.Errorf("expected skipped lines %v, got %v", tt.wantLines, lines)
+			}
+		})
+	}
+}
+
+func TestSkippedCuesError(t *testing.T) {
+	err := &SkippedCuesError{
+		Errors: []*ParseError{
+			{Source: "a.txt", Line: 2, Column: 2, Err: ErrInvalidFrame},
+			{Source: "a.txt", Line: 4, Column: 1, Err: ErrMissingBrace},
+		},
+	}
+
+	expected := "skipped 2 malformed cue(s):\n" +
+		"a.txt:2:2: " + ErrInvalidFrame.Error() + "\n" +
+		"a.txt:4:1: " + ErrMissingBrace.Error()
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	if !errors.Is(err, ErrMissingBrace) {
+		t.Error("expected errors.Is to match a skipped cue error")
+	}
+}
@@ -0,0 +1,402 @@
+package subtitle
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubViewer 2.0 times cues in centiseconds on a line of their own, e.g.
+// 00:00:01.00,00:00:03.50, followed by text with [br] line breaks. SubViewer
+// 1.0 puts the start and end times on the lines around the text, e.g.
+// [00:00:01], and breaks lines with '|'.
+const (
+	subviewerInformation    = "[INFORMATION]"
+	subviewerEndInformation = "[END INFORMATION]"
+	subviewerSubtitle       = "[SUBTITLE]"
+	subviewerBreak          = "[br]"
+	// subviewerStyleKey is the key of the style line, e.g.
+	// [COLF]&HFFFFFF,[STYLE]bd,[SIZE]18,[FONT]Arial, written after the
+	// [SUBTITLE] marker
+	subviewerStyleKey = "COLF"
+)
+
+var (
+	subviewerTimingPattern = regexp.MustCompile(
+		`^(\d{1,2}:\d\d:\d\d\.\d\d),(\d{1,2}:\d\d:\d\d\.\d\d)$`,
+	)
+	subviewer1TimePattern = regexp.MustCompile(`^\[(\d\d):(\d\d):(\d\d)\]$`)
+	subviewerFieldPattern = regexp.MustCompile(`^\[([^\]]+)\](.*)$`)
+	subviewerBreakPattern = regexp.MustCompile(`(?i)\[br\]`)
+	// subviewerRate counts the centiseconds of SubViewer 2.0 times
+	subviewerRate = FrameRate{Num: 100, Den: 1}
+	// Fields written when the document was not read from SubViewer
+	subviewerFields = []string{
+		"TITLE", "AUTHOR", "SOURCE", "PRG", "FILEPATH", "DELAY", "CD TRACK",
+		"COMMENT",
+	}
+)
+
+// isSubviewerLine reports whether a trimmed line is the SubViewer 2.0 header
+// or a SubViewer timing line.
+func isSubviewerLine(line string) bool {
+	return strings.EqualFold(line, subviewerInformation) ||
+		subviewerTimingPattern.MatchString(line) ||
+		subviewer1TimePattern.MatchString(line)
+}
+
+// isSubviewerMarker reports whether a trimmed line only marks a part of the
+// file, e.g. [END INFORMATION] or the "**** START SCRIPT ****" of SubViewer
+// 1.0.
+func isSubviewerMarker(line string) bool {
+	upper := strings.ToUpper(line)
+
+	switch upper {
+	case subviewerInformation, subviewerEndInformation, subviewerSubtitle,
+		"[BEGIN]", "[END]":
+		return true
+	}
+
+	return strings.Contains(upper, "START SCRIPT") ||
+		strings.Contains(upper, "END SCRIPT")
+}
+
+// parseSubviewer1Time parses a SubViewer 1.0 time line, e.g. [00:01:02].
+func parseSubviewer1Time(line string) (time.Duration, bool) {
+	match := subviewer1TimePattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, false
+	}
+
+	var clock [3]int
+	for i := range clock {
+		clock[i], _ = strconv.Atoi(match[i+1])
+	}
+
+	if clock[1] > 59 || clock[2] > 59 {
+		return 0, false
+	}
+
+	return time.Duration(clock[0])*time.Hour +
+		time.Duration(clock[1])*time.Minute +
+		time.Duration(clock[2])*time.Second, true
+}
+
+func parseSubviewerTiming(line string) (start, end time.Duration, err error) {
+	match := subviewerTimingPattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, 0, &ParseError{
+			Column: 1,
+			Err:    errors.New("expected HH:MM:SS.cc,HH:MM:SS.cc"),
+		}
+	}
+
+	if start, err = parseSrtDuration(match[1]); err != nil {
+		return 0, 0, &ParseError{
+			Column: 1,
+			Err:    &valueError{field: "start time", value: match[1], err: err},
+		}
+	}
+
+	if end, err = parseSrtDuration(match[2]); err != nil {
+		return 0, 0, &ParseError{
+			Column: len(match[1]) + 2,
+			Err:    &valueError{field: "end time", value: match[2], err: err},
+		}
+	}
+
+	return start, end, nil
+}
+
+// parseSubviewerField reads a header line into doc. A line without a
+// [KEY] is the value of a preceding key without one, as in SubViewer 1.0.
+func parseSubviewerField(doc *Document, line string) {
+	match := subviewerFieldPattern.FindStringSubmatch(line)
+	if match == nil {
+		last := len(doc.Metadata) - 1
+		if last >= 0 && doc.Metadata[last].Key != "" &&
+			doc.Metadata[last].Value == "" {
+			doc.Metadata[last].Value = line
+			if strings.EqualFold(doc.Metadata[last].Key, "TITLE") {
+				doc.Title = line
+			}
+
+			return
+		}
+
+		doc.Metadata = append(doc.Metadata, MetadataField{Value: line})
+
+		return
+	}
+
+	key, value := match[1], match[2]
+	if strings.EqualFold(key, "TITLE") {
+		doc.Title = value
+	}
+
+	doc.Metadata = append(doc.Metadata, MetadataField{Key: key, Value: value})
+}
+
+func newSubtitleFromSubviewer(
+	line string,
+	readLine func() (string, bool),
+) (sub Subtitle, err error) {
+	// Parse block:
+	// 00:00:01.00,00:00:03.50
+	// text[br]text
+	//
+	sub.Start, sub.End, err = parseSubviewerTiming(line)
+	if err != nil {
+		return sub, err
+	}
+
+	text := strings.Join(readBlock(readLine), "\n")
+	sub.Text = subviewerBreakPattern.ReplaceAllLiteralString(text, "\n")
+
+	return sub, nil
+}
+
+// readSubviewer1Cue reads the text after a SubViewer 1.0 start time and the
+// end time after it. A cue without an end time is shown for display. A time
+// right after the start one starts the next cue, and is returned as next.
+func readSubviewer1Cue(
+	start, display time.Duration,
+	readLine func() (string, bool),
+) (sub Subtitle, next string) {
+	// Parse block:
+	// [00:00:01]
+	// text|text
+	// [00:00:03]
+	var lines []string
+
+	sub.Start, sub.End = start, start+display
+
+	for {
+		line, ok := readLine()
+		if !ok || strings.TrimSpace(line) == "" {
+			break
+		}
+
+		if end, found := parseSubviewer1Time(strings.TrimSpace(line)); found {
+			if len(lines) == 0 {
+				return sub, line
+			}
+
+			sub.End = end
+
+			break
+		}
+
+		lines = append(lines, strings.ReplaceAll(line, "|", "\n"))
+	}
+
+	sub.Text = strings.Join(lines, "\n")
+
+	return sub, ""
+}
+
+func newSubviewerSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		defer stop()
+
+		reader := &lineReader{next: next, name: config.Name}
+		errs := &errorPolicy{lenient: config.Lenient}
+		doc := config.document()
+
+		// Lines before the first cue are the header. In SubViewer 1.0 a
+		// time right after a start time is kept in first to start the
+		// next cue.
+		header := true
+
+		var first string
+
+		for reader.err == nil {
+			line, ok := first, first != ""
+			if first = ""; !ok {
+				line, ok = reader.skipBlank()
+			}
+
+			if !ok {
+				break
+			}
+
+			line = strings.TrimSpace(line)
+
+			switch {
+			case isSubviewerMarker(line):
+				continue
+			case header && !isSubviewerLine(line):
+				parseSubviewerField(doc, line)
+				continue
+			}
+
+			header = false
+
+			var (
+				sub Subtitle
+				err error
+			)
+
+			if start, found := parseSubviewer1Time(line); found {
+				sub, first = readSubviewer1Cue(
+					start,
+					config.displayTime(),
+					reader.readLine,
+				)
+
+				// A time without text only ends the previous cue
+				if sub.Text == "" {
+					continue
+				}
+			} else {
+				sub, err = newSubtitleFromSubviewer(line, reader.readLine)
+			}
+
+			if reader.err != nil {
+				break
+			}
+
+			if err != nil {
+				if parseErr := reader.parseError(err); !errs.skip(parseErr) {
+					yield(Subtitle{}, parseErr)
+					return
+				}
+
+				// Resume at the next block
+				skipBlock(reader.readLine)
+
+				continue
+			}
+
+			if !yield(sub, nil) {
+				return
+			}
+		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading subviewer subtitle: %w", reader.err),
+			)
+
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+// writeSubviewerDuration writes a SubViewer 2.0 time, rounded to
+// centiseconds.
+func writeSubviewerDuration(w io.Writer, d time.Duration) error {
+	centis := subviewerRate.durationToFrame(d)
+	_, err := fmt.Fprintf(
+		w,
+		"%02d:%02d:%02d.%02d",
+		centis/360000,
+		centis/6000%60,
+		centis/100%60,
+		centis%100,
+	)
+
+	return err
+}
+
+func writeSubviewerSubtitle(w io.Writer, sub Subtitle) error {
+	if err := writeSubviewerDuration(w, sub.Start); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(w, ","); err != nil {
+		return err
+	}
+
+	if err := writeSubviewerDuration(w, sub.End); err != nil {
+		return err
+	}
+
+	text := strings.ReplaceAll(sub.Text, "\n", subviewerBreak)
+	_, err := fmt.Fprintf(w, "\n%s\n\n", text)
+
+	return err
+}
+
+// writeSubviewerHeader writes the SubViewer 2.0 header, with the fields read
+// from a SubViewer document or the usual empty ones.
+func writeSubviewerHeader(w io.Writer, doc *Document) error {
+	metadata, _ := doc.metadata(SubviewerFormat)
+	if len(metadata) == 0 {
+		for _, key := range subviewerFields {
+			metadata = append(metadata, MetadataField{Key: key})
+		}
+	}
+
+	var style []MetadataField
+
+	if _, err := fmt.Fprintln(w, subviewerInformation); err != nil {
+		return err
+	}
+
+	for _, field := range metadata {
+		var err error
+
+		switch {
+		case field.Key == "":
+			_, err = fmt.Fprintln(w, field.Value)
+		case strings.EqualFold(field.Key, subviewerStyleKey):
+			style = append(style, field)
+		case strings.EqualFold(field.Key, "TITLE"):
+			value := cmp.Or(doc.Title, field.Value)
+			_, err = fmt.Fprintf(w, "[%s]%s\n", field.Key, value)
+		default:
+			_, err = fmt.Fprintf(w, "[%s]%s\n", field.Key, field.Value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"%s\n%s\n",
+		subviewerEndInformation,
+		subviewerSubtitle,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range style {
+		_, err = fmt.Fprintf(w, "[%s]%s\n", field.Key, field.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w)
+
+	return err
+}
+
+func newSubviewerPrinter(writer io.Writer, config PrinterConfig) Printer {
+	return &printer{
+		writer: writer,
+		header: func(w io.Writer, _ *Subtitle) error {
+			return writeSubviewerHeader(w, config.document())
+		},
+		write: writeSubviewerSubtitle,
+	}
+}
@@ -0,0 +1,192 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const subviewerInput = "[INFORMATION]\r\n" +
+	"[TITLE]Movie\r\n" +
+	"[AUTHOR]Someone\r\n" +
+	"[DELAY]0\r\n" +
+	"[END INFORMATION]\r\n" +
+	"[SUBTITLE]\r\n" +
+	"[COLF]&HFFFFFF,[STYLE]bd,[SIZE]18,[FONT]Arial\r\n" +
+	"00:00:01.00,00:00:03.50\r\n" +
+	"First line[br]Second line\r\n" +
+	"\r\n" +
+	"00:01:04.25,00:01:06.00\r\n" +
+	"Two\r\n" +
+	"lines[BR]three\r\n"
+
+func TestNewSubtitlesIter_SubviewerFormat(t *testing.T) {
+	var (
+		doc  Document
+		subs []Subtitle
+	)
+
+	config := ReaderConfig{Document: &doc}
+	for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(subviewerInput), SubviewerFormat, config) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	want := []Subtitle{
+		{Start: time.Second, End: 3500 * time.Millisecond, Text: "First line\nSecond line"},
+		{Start: time.Minute + 4250*time.Millisecond, End: time.Minute + 6*time.Second, Text: "Two\nlines\nthree"},
+	}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("expected %+v, got %+v", want, subs)
+	}
+
+	if doc.Title != "Movie" || len(doc.Metadata) != 4 || doc.Metadata[3].Key != "COLF" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+}
+
+func TestNewSubtitlesIter_SubviewerFormat_Version1(t *testing.T) {
+	input := "[TITLE]\n" +
+		"Movie\n" +
+		"[AUTHOR]\n" +
+		"\n" +
+		"[BEGIN]\n" +
+		"******** START SCRIPT ********\n" +
+		"[00:00:01]\n" +
+		"First|line\n" +
+		"[00:00:02]\n" +
+		"\n" +
+		"[00:00:05]\n" +
+		"[00:00:06]\n" +
+		"Open ended\n" +
+		"\n" +
+		"[end]\n" +
+		"******** END SCRIPT ********\n"
+
+	var (
+		doc  Document
+		subs []Subtitle
+	)
+
+	config := ReaderConfig{Document: &doc}
+	for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(input), SubviewerFormat, config) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	want := []Subtitle{
+		{Start: time.Second, End: 2 * time.Second, Text: "First\nline"},
+		{Start: 6 * time.Second, End: 9 * time.Second, Text: "Open ended"},
+	}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("expected %+v, got %+v", want, subs)
+	}
+
+	if doc.Title != "Movie" {
+		t.Errorf("expected title %q, got %q", "Movie", doc.Title)
+	}
+}
+
+func TestNewSubtitlesIter_SubviewerFormat_Errors(t *testing.T) {
+	input := "[INFORMATION]\n[END INFORMATION]\n" +
+		"00:00:01.00,00:00:02.00\nFirst\n\n" +
+		"00:00:03.00-00:00:04.00\nBroken\n\n" +
+		"00:00:05.00,00:00:06.00\nLast\n"
+
+	var parseErr *ParseError
+
+	for _, err := range NewSubtitlesIter(strings.NewReader(input), SubviewerFormat) {
+		if err != nil && !errors.As(err, &parseErr) {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+	}
+
+	if parseErr == nil || parseErr.Line != 6 {
+		t.Fatalf("expected an error on line 6, got %v", parseErr)
+	}
+
+	var (
+		texts   []string
+		skipped *SkippedCuesError
+	)
+
+	subs := NewSubtitlesIterWithConfig(strings.NewReader(input), SubviewerFormat, ReaderConfig{Lenient: true})
+	for sub, err := range subs {
+		if errors.As(err, &skipped) {
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if !reflect.DeepEqual(texts, []string{"First", "Last"}) || skipped == nil || len(skipped.Errors) != 1 {
+		t.Errorf("expected 2 cues and 1 skipped, got %q, %v", texts, skipped)
+	}
+}
+
+func TestNewSubtitlePrinter_SubviewerFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	printer := NewSubtitlePrinterWithConfig(&buf, SubviewerFormat, PrinterConfig{Document: &Document{Title: "Movie"}})
+
+	sub := Subtitle{Start: 1234 * time.Millisecond, End: time.Hour + 5*time.Millisecond, Text: "One\nTwo"}
+	if err := printer.Write(sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "[INFORMATION]\n[TITLE]Movie\n[AUTHOR]\n[SOURCE]\n[PRG]\n[FILEPATH]\n[DELAY]\n[CD TRACK]\n[COMMENT]\n" +
+		"[END INFORMATION]\n[SUBTITLE]\n\n" +
+		"00:00:01.23,01:00:00.01\nOne[br]Two\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestNewSubtitlePrinter_SubviewerFormat_RoundTrip(t *testing.T) {
+	var (
+		doc Document
+		buf bytes.Buffer
+	)
+
+	printer := NewSubtitlePrinterWithConfig(&buf, SubviewerFormat, PrinterConfig{Document: &doc})
+
+	config := ReaderConfig{Document: &doc}
+	for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(subviewerInput), SubviewerFormat, config) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "[INFORMATION]\n[TITLE]Movie\n[AUTHOR]Someone\n[DELAY]0\n[END INFORMATION]\n[SUBTITLE]\n" +
+		"[COLF]&HFFFFFF,[STYLE]bd,[SIZE]18,[FONT]Arial\n\n" +
+		"00:00:01.00,00:00:03.50\nFirst line[br]Second line\n\n" +
+		"00:01:04.25,00:01:06.00\nTwo[br]lines[br]three\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
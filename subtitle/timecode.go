@@ -0,0 +1,151 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidTimecode = errors.New("invalid timecode")
+
+// timebase is the whole number of frames counted per timecode second, e.g.
+// 30 for 29.97 fps.
+func (r FrameRate) timebase() int64 {
+	return (r.Num + r.Den - 1) / r.Den
+}
+
+// droppedFrames returns the frame numbers skipped at the start of each
+// minute not divisible by ten, or zero for rates without drop-frame.
+func (r FrameRate) droppedFrames() int64 {
+	if r.Den != 1001 || r.Num%30000 != 0 {
+		return 0
+	}
+
+	// 2 at 29.97 fps, 4 at 59.94 fps
+	return r.Num / 15000
+}
+
+// SupportsDropFrame reports whether the rate has a drop-frame timecode,
+// which is the case for 29.97 and 59.94 fps.
+func (r FrameRate) SupportsDropFrame() bool {
+	return r.droppedFrames() > 0
+}
+
+// FormatTimecode formats a frame number as an SMPTE timecode, HH:MM:SS:FF
+// or HH:MM:SS;FF for drop-frame. Drop-frame timecodes skip frame numbers so
+// that they stay in step with the clock at 29.97 and 59.94 fps.
+func (r FrameRate) FormatTimecode(frame int64, dropFrame bool) (string, error) {
+	if r.IsZero() {
+		return "", fmt.Errorf("%w: frame rate is not set", ErrInvalidTimecode)
+	}
+
+	if frame < 0 {
+		return "", fmt.Errorf(
+			"%w: negative frame %d",
+			ErrInvalidTimecode,
+			frame,
+		)
+	}
+
+	base, sep := r.timebase(), ":"
+
+	if dropFrame {
+		drop := r.droppedFrames()
+		if drop == 0 {
+			return "", fmt.Errorf(
+				"%w: no drop-frame timecode at %s fps",
+				ErrInvalidTimecode,
+				r,
+			)
+		}
+
+		// Add back the frame numbers skipped so far
+		perMinute := base*60 - drop
+		perTenMinutes := base*600 - drop*9
+
+		tens, rest := frame/perTenMinutes, frame%perTenMinutes
+		frame += drop * 9 * tens
+		if rest > drop {
+			frame += drop * ((rest - drop) / perMinute)
+		}
+
+		sep = ";"
+	}
+
+	return fmt.Sprintf(
+		"%02d:%02d:%02d%s%02d",
+		frame/(base*3600),
+		frame/(base*60)%60,
+		frame/base%60,
+		sep,
+		frame%base,
+	), nil
+}
+
+// ParseTimecode parses an SMPTE timecode into a frame number. A semicolon
+// before the frames marks a drop-frame timecode, which is only valid at 29.97
+// and 59.94 fps.
+func (r FrameRate) ParseTimecode(value string) (int64, error) {
+	invalid := func(reason string) (int64, error) {
+		return 0, fmt.Errorf("%w %q: %s", ErrInvalidTimecode, value, reason)
+	}
+
+	if r.IsZero() {
+		return invalid("frame rate is not set")
+	}
+
+	clock, frames, dropFrame := strings.Cut(value, ";")
+	if !dropFrame {
+		i := strings.LastIndex(value, ":")
+		if i < 0 {
+			return invalid("expected HH:MM:SS:FF")
+		}
+
+		clock, frames = value[:i], value[i+1:]
+	}
+
+	parts := strings.Split(clock, ":")
+	if len(parts) != 3 {
+		return invalid("expected HH:MM:SS:FF")
+	}
+
+	parts = append(parts, frames)
+
+	var fields [4]int64
+
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || len(part) < 2 {
+			return invalid("expected two-digit fields")
+		}
+
+		fields[i] = int64(n)
+	}
+
+	base := r.timebase()
+	hours, minutes, seconds, frame := fields[0], fields[1], fields[2], fields[3]
+
+	if minutes > 59 || seconds > 59 || frame >= base {
+		return invalid("field out of range")
+	}
+
+	total := ((hours*60+minutes)*60+seconds)*base + frame
+
+	if !dropFrame {
+		return total, nil
+	}
+
+	drop := r.droppedFrames()
+	if drop == 0 {
+		return invalid(fmt.Sprintf("no drop-frame timecode at %s fps", r))
+	}
+
+	if seconds == 0 && frame < drop && minutes%10 != 0 {
+		return invalid("frame number dropped in drop-frame timecode")
+	}
+
+	allMinutes := hours*60 + minutes
+
+	return total - drop*(allMinutes-allMinutes/10), nil
+}
@@ -0,0 +1,107 @@
+package subtitle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFrameRate_FormatTimecode(t *testing.T) {
+	ntsc := FrameRate{30000, 1001}
+
+	tests := []struct {
+		rate      FrameRate
+		frame     int64
+		dropFrame bool
+		want      string
+	}{
+		{FrameRate{25, 1}, 0, false, "00:00:00:00"},
+		{FrameRate{25, 1}, 90000 + 24, false, "01:00:00:24"},
+		{FrameRate{24000, 1001}, 24*61 + 3, false, "00:01:01:03"},
+		{ntsc, 1800, false, "00:01:00:00"},
+		{ntsc, 1799, true, "00:00:59;29"},
+		{ntsc, 1800, true, "00:01:00;02"},
+		{ntsc, 3598, true, "00:02:00;02"},
+		{ntsc, 17982, true, "00:10:00;00"},
+		{ntsc, 17981, true, "00:09:59;29"},
+		{ntsc, 107892, true, "01:00:00;00"},
+		{FrameRate{60000, 1001}, 3600, true, "00:01:00;04"},
+		{FrameRate{60000, 1001}, 35964, true, "00:10:00;00"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.rate.FormatTimecode(tt.frame, tt.dropFrame)
+		if err != nil {
+			t.Errorf("%v: frame %d: unexpected error: %v", tt.rate, tt.frame, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%v: frame %d: expected %q, got %q", tt.rate, tt.frame, tt.want, got)
+		}
+
+		frame, err := tt.rate.ParseTimecode(got)
+		if err != nil || frame != tt.frame {
+			t.Errorf("%v: %q: expected frame %d, got %d, %v", tt.rate, got, tt.frame, frame, err)
+		}
+	}
+}
+
+func TestFrameRate_TimecodeRoundTrip(t *testing.T) {
+	for _, rate := range []FrameRate{{30000, 1001}, {60000, 1001}} {
+		// Every frame of the first 3 hours
+		last := rate.Frame(3*time.Hour, RoundUp)
+
+		for frame := range last {
+			tc, err := rate.FormatTimecode(frame, true)
+			if err != nil {
+				t.Fatalf("%v: frame %d: unexpected error: %v", rate, frame, err)
+			}
+
+			got, err := rate.ParseTimecode(tc)
+			if err != nil || got != frame {
+				t.Fatalf("%v: %q: expected frame %d, got %d, %v", rate, tc, frame, got, err)
+			}
+		}
+	}
+}
+
+func TestFrameRate_ParseTimecode_Errors(t *testing.T) {
+	tests := []struct {
+		rate  FrameRate
+		value string
+	}{
+		{FrameRate{}, "00:00:00:00"},
+		{FrameRate{25, 1}, ""},
+		{FrameRate{25, 1}, "00:00:00"},
+		{FrameRate{25, 1}, "00:00:00:25"},
+		{FrameRate{25, 1}, "00:60:00:00"},
+		{FrameRate{25, 1}, "00:00:60:00"},
+		{FrameRate{25, 1}, "0:0:0:0"},
+		{FrameRate{25, 1}, "00:00:00:-1"},
+		{FrameRate{25, 1}, "00:00:00;00"},
+		{FrameRate{30000, 1001}, "00:01:00;00"},
+		{FrameRate{30000, 1001}, "00:01:00;01"},
+		{FrameRate{60000, 1001}, "00:01:00;03"},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.rate.ParseTimecode(tt.value); !errors.Is(err, ErrInvalidTimecode) {
+			t.Errorf("%v: %q: expected ErrInvalidTimecode, got %v", tt.rate, tt.value, err)
+		}
+	}
+}
+
+func TestFrameRate_FormatTimecode_Errors(t *testing.T) {
+	if _, err := (FrameRate{25, 1}).FormatTimecode(0, true); !errors.Is(err, ErrInvalidTimecode) {
+		t.Errorf("expected no drop-frame at 25 fps, got %v", err)
+	}
+
+	if _, err := (FrameRate{25, 1}).FormatTimecode(-1, false); !errors.Is(err, ErrInvalidTimecode) {
+		t.Errorf("expected error for a negative frame, got %v", err)
+	}
+
+	if !(FrameRate{30000, 1001}).SupportsDropFrame() || (FrameRate{24000, 1001}).SupportsDropFrame() {
+		t.Error("expected drop-frame support at 29.97 fps only")
+	}
+}
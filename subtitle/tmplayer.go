@@ -0,0 +1,252 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// clockDialect is TMPlayer or VPlayer, whose cues are lines of a start time
+// and text, e.g. 00:01:02:text. Cues end by the display policy, or at a line
+// without text.
+type clockDialect struct {
+	// name of the format in read errors
+	name string
+	// policy is the display policy of the format
+	policy DisplayPolicy
+}
+
+var (
+	tmplayer = clockDialect{name: "tmplayer", policy: DisplayFixed}
+	vplayer  = clockDialect{name: "vplayer", policy: DisplayUntilNext}
+	// The time is followed by ':', '=' or, in multiline TMPlayer, by the
+	// line number and '=', e.g. 00:01:02,1=text
+	clockLinePattern = regexp.MustCompile(
+		`^(\d{1,2}):(\d\d):(\d\d)(?:,(\d))?[:=]`,
+	)
+	// clockRate counts the whole seconds of the times
+	clockRate = FrameRate{Num: 1, Den: 1}
+)
+
+// clockLine is a tokenized TMPlayer or VPlayer line.
+type clockLine struct {
+	start time.Duration
+	// part numbers the lines of multiline TMPlayer cues, or is zero
+	part int
+	text string
+}
+
+func parseClockLine(line string) (parsed clockLine, err error) {
+	// Parse format: 00:01:02:text, 0:01:02=text or 00:01:02,1=text
+	line = strings.TrimRightFunc(line, unicode.IsSpace)
+	rest := strings.TrimLeft(strings.TrimPrefix(line, byteOrderMark), " \t")
+
+	if rest == "" {
+		return parsed, ErrEmptyLine
+	}
+
+	column := len(line) - len(rest) + 1
+
+	match := clockLinePattern.FindStringSubmatch(rest)
+	if match == nil {
+		return parsed, &ParseError{
+			Column: column,
+			Text:   line,
+			Err:    errors.New("expected H:MM:SS: before the text"),
+		}
+	}
+
+	var clock [3]int
+	for i := range clock {
+		clock[i], _ = strconv.Atoi(match[i+1])
+	}
+
+	if clock[1] > 59 || clock[2] > 59 {
+		return parsed, &ParseError{
+			Column: column,
+			Text:   line,
+			Err: &valueError{
+				field: "start time",
+				value: strings.Join(match[1:4], ":"),
+				err:   strconv.ErrRange,
+			},
+		}
+	}
+
+	parsed.start = time.Duration(clock[0])*time.Hour +
+		time.Duration(clock[1])*time.Minute +
+		time.Duration(clock[2])*time.Second
+
+	if match[4] != "" {
+		parsed.part, _ = strconv.Atoi(match[4])
+	}
+
+	parsed.text = rest[len(match[0]):]
+
+	return parsed, nil
+}
+
+func (d clockDialect) subtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		defer stop()
+
+		var (
+			reader = &lineReader{next: next, name: config.Name}
+			errs   = &errorPolicy{lenient: config.Lenient}
+			// pending is the cue read last, waiting for its end
+			pending *clockLine
+		)
+
+		// end yields the pending cue, ended by a line at next, which is
+		// without text when explicit
+		end := func(next time.Duration, explicit bool) bool {
+			spans := parseSlashText(pending.text)
+			sub := Subtitle{
+				Start: pending.start,
+				End:   pending.start + config.displayTime(),
+				Text:  spansText(spans),
+				Spans: styledText(spans),
+			}
+
+			switch {
+			case explicit && next >= sub.Start:
+				sub.End = next
+			case !explicit:
+				config.endOpenCue(&sub, next, d.policy)
+			}
+
+			pending = nil
+
+			return yield(sub, nil)
+		}
+
+		for {
+			line, ok := reader.readLine()
+			if !ok {
+				break
+			}
+
+			parsed, err := parseClockLine(line)
+			if errors.Is(err, ErrEmptyLine) {
+				continue
+			}
+
+			if err != nil {
+				if parseErr := reader.parseError(err); !errs.skip(parseErr) {
+					yield(Subtitle{}, parseErr)
+					return
+				}
+
+				continue
+			}
+
+			// Further lines of a multiline TMPlayer cue
+			if pending != nil && parsed.part > 1 &&
+				parsed.start == pending.start {
+				pending.text += "|" + parsed.text
+				continue
+			}
+
+			if pending != nil && !end(parsed.start, parsed.text == "") {
+				return
+			}
+
+			if parsed.text != "" {
+				pending = &parsed
+			}
+		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading %s subtitle: %w", d.name, reader.err),
+			)
+			return
+		}
+
+		// The last cue is shown for the display time
+		if pending != nil && !end(-1, false) {
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+func newTmplayerSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return tmplayer.subtitlesIter(next, stop, config)
+}
+
+func newVplayerSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return vplayer.subtitlesIter(next, stop, config)
+}
+
+// writeClockLine writes a TMPlayer or VPlayer line, rounding the time to
+// whole seconds.
+func writeClockLine(w io.Writer, d time.Duration, text string) error {
+	seconds := clockRate.durationToFrame(d)
+	_, err := fmt.Fprintf(
+		w,
+		"%02d:%02d:%02d:%s\n",
+		seconds/3600,
+		seconds/60%60,
+		seconds%60,
+		text,
+	)
+
+	return err
+}
+
+// newClockPrinter returns a TMPlayer or VPlayer printer. The end of a cue is
+// written as a line without text, unless the next cue starts by then.
+func newClockPrinter(writer io.Writer, _ PrinterConfig) Printer {
+	var (
+		// end of the previous cue, when written
+		end     time.Duration
+		written bool
+	)
+
+	return &printer{
+		writer: writer,
+		write: func(w io.Writer, sub Subtitle) error {
+			if written &&
+				clockRate.durationToFrame(end) <
+					clockRate.durationToFrame(sub.Start) {
+				if err := writeClockLine(w, end, ""); err != nil {
+					return err
+				}
+			}
+
+			end, written = sub.End, true
+
+			return writeClockLine(w, sub.Start, formatSlashText(sub))
+		},
+		footer: func(w io.Writer) error {
+			if !written {
+				return nil
+			}
+
+			return writeClockLine(w, end, "")
+		},
+	}
+}
@@ -0,0 +1,214 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClockLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want clockLine
+	}{
+		{"00:01:02:Text", clockLine{start: time.Minute + 2*time.Second, text: "Text"}},
+		{"1:00:00=Text|Two", clockLine{start: time.Hour, text: "Text|Two"}},
+		{"00:00:05,2=Second", clockLine{start: 5 * time.Second, part: 2, text: "Second"}},
+		{"\uFEFF  00:00:05:  ", clockLine{start: 5 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseClockLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseClockLine_Errors(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantMsg string
+	}{
+		{"Text", "1: expected H:MM:SS: before the text"},
+		{"00:01 Text", "1: expected H:MM:SS: before the text"},
+		{"  00:75:00:Text", `3: invalid start time "00:75:00"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			_, err := parseClockLine(tt.line)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected ParseError, got %v", err)
+			}
+
+			if !strings.HasSuffix(parseErr.Error(), tt.wantMsg) {
+				t.Errorf("expected %q, got %q", tt.wantMsg, parseErr.Error())
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_TmplayerFormat(t *testing.T) {
+	input := "00:00:01:/First|Second\n" +
+		"00:00:02,1=Multi\n" +
+		"00:00:02,2=line\n" +
+		"00:00:10:Cleared\n" +
+		"00:00:11:\n" +
+		"00:00:20:{y:b}Last\n"
+
+	tests := []struct {
+		name   string
+		format FileFormat
+		config ReaderConfig
+		ends   []time.Duration
+	}{
+		{
+			name:   "tmplayer displays for 3 seconds",
+			format: TmplayerFormat,
+			ends:   []time.Duration{2 * time.Second, 5 * time.Second, 11 * time.Second, 23 * time.Second},
+		},
+		{
+			name:   "vplayer displays until the next cue",
+			format: VplayerFormat,
+			ends:   []time.Duration{2 * time.Second, 10 * time.Second, 11 * time.Second, 23 * time.Second},
+		},
+		{
+			name:   "configured policy and time",
+			format: TmplayerFormat,
+			config: ReaderConfig{DisplayPolicy: DisplayUntilNext, DisplayTime: time.Second},
+			ends:   []time.Duration{2 * time.Second, 10 * time.Second, 11 * time.Second, 21 * time.Second},
+		},
+		{
+			name:   "configured time",
+			format: VplayerFormat,
+			config: ReaderConfig{DisplayPolicy: DisplayFixed, DisplayTime: 5 * time.Second},
+			ends:   []time.Duration{2 * time.Second, 7 * time.Second, 11 * time.Second, 25 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				texts []string
+				ends  []time.Duration
+			)
+
+			subs := NewSubtitlesIterWithConfig(strings.NewReader(input), tt.format, tt.config)
+			for sub, err := range subs {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				texts = append(texts, sub.Text)
+				ends = append(ends, sub.End)
+
+				if sub.Text == "First\nSecond" &&
+					!reflect.DeepEqual(sub.spans(), []Span{{Text: "First", Italic: true}, {Text: "\nSecond"}}) {
+					t.Errorf("unexpected spans %+v", sub.spans())
+				}
+			}
+
+			// MicroDVD control codes are text
+			wantTexts := []string{"First\nSecond", "Multi\nline", "Cleared", "{y:b}Last"}
+			if !reflect.DeepEqual(texts, wantTexts) {
+				t.Errorf("expected %q, got %q", wantTexts, texts)
+			}
+
+			if !reflect.DeepEqual(ends, tt.ends) {
+				t.Errorf("expected ends %v, got %v", tt.ends, ends)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_TmplayerFormat_Lenient(t *testing.T) {
+	input := "00:00:01:First\nbroken\n00:00:05:Second\n"
+
+	var (
+		texts   []string
+		skipped *SkippedCuesError
+	)
+
+	subs := NewSubtitlesIterWithConfig(strings.NewReader(input), TmplayerFormat, ReaderConfig{Lenient: true})
+	for sub, err := range subs {
+		if errors.As(err, &skipped) {
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if len(texts) != 2 || skipped == nil || len(skipped.Errors) != 1 || skipped.Errors[0].Line != 2 {
+		t.Errorf("expected 2 cues and line 2 skipped, got %q, %v", texts, skipped)
+	}
+}
+
+func TestNewSubtitlePrinter_TmplayerFormat(t *testing.T) {
+	subs := []Subtitle{
+		{Start: 1 * time.Second, End: 2 * time.Second, Text: "First"},
+		{
+			Start: 2400 * time.Millisecond,
+			End:   4 * time.Second,
+			Text:  "Italic\nline",
+			Spans: &StyledText{{Text: "Italic", Italic: true}, {Text: "\nline"}},
+		},
+		{Start: 4 * time.Second, End: 3*time.Hour + 5*time.Second, Text: "Long"},
+	}
+
+	for _, format := range []FileFormat{TmplayerFormat, VplayerFormat} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+
+			printer := NewSubtitlePrinter(&buf, format)
+			for _, sub := range subs {
+				if err := printer.Write(sub); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if err := printer.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := "00:00:01:First\n" +
+				"00:00:02:/Italic|line\n" +
+				"00:00:04:Long\n" +
+				"03:00:05:\n"
+			if got := buf.String(); got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+
+			// The ends survive a round trip, as far as whole seconds go
+			var ends []time.Duration
+
+			for sub, err := range NewSubtitlesIter(strings.NewReader(want), format) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				ends = append(ends, sub.End)
+			}
+
+			wantEnds := []time.Duration{2 * time.Second, 4 * time.Second, 3*time.Hour + 5*time.Second}
+			if !reflect.DeepEqual(ends, wantEnds) {
+				t.Errorf("expected ends %v, got %v", wantEnds, ends)
+			}
+		})
+	}
+}
@@ -0,0 +1,1072 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TTML namespaces; DFXP files use the older ones of the 2006 drafts, which
+// are read the same way, as elements and attributes are matched by name.
+const (
+	ttmlNamespace          = "http://www.w3.org/ns/ttml"
+	ttmlStylingNamespace   = ttmlNamespace + "#styling"
+	ttmlParameterNamespace = ttmlNamespace + "#parameter"
+	ttmlMetadataNamespace  = ttmlNamespace + "#metadata"
+	// ttmlLineHeight is the height of a WebVTT region line in percent of
+	// the video height, used to convert region heights
+	ttmlLineHeight = 5.33
+	// ttmlFrameRate is the frame rate when ttp:frameRate is not set
+	ttmlFrameRate = 30
+)
+
+var (
+	// ttmlSniffPattern matches the <tt> element at the start of a line, or
+	// after the XML declaration of a single-line document
+	ttmlSniffPattern = regexp.MustCompile(
+		`^(?:<\?xml[^>]*\?>\s*)?<(?:\w+:)?tt(?:[\s>]|$)`,
+	)
+	ttmlClockPattern = regexp.MustCompile(
+		`^(\d{2,}):(\d\d):(\d\d)(?:(\.\d+)|:(\d{2,})(?:\.\d+)?)?$`,
+	)
+	ttmlOffsetPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|ms|m|s|f|t)$`)
+	ttmlEscape        = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+	).Replace
+)
+
+// ttmlHead is the <head> of a TTML document.
+type ttmlHead struct {
+	Title   string           `xml:"metadata>title"`
+	Styles  []ttmlDefinition `xml:"styling>style"`
+	Regions []ttmlDefinition `xml:"layout>region"`
+}
+
+// ttmlDefinition is a <style> or <region> with its styling attributes.
+type ttmlDefinition struct {
+	ID    string     `xml:"id,attr"`
+	Attrs []xml.Attr `xml:",any,attr"`
+}
+
+// ttmlTiming holds the parameters of time expressions, e.g. 00:00:01:12 or
+// 3000t.
+type ttmlTiming struct {
+	frameRate FrameRate
+	tickRate  FrameRate
+}
+
+// ttmlContext is the timing, style and region an element passes on to its
+// content. Every element is read as a par time container, so that times
+// are relative to the begin of the parent.
+type ttmlContext struct {
+	begin time.Duration
+	// end is negative while open
+	end      time.Duration
+	style    Span
+	region   string
+	preserve bool
+}
+
+// ttmlText collects the spans of a paragraph, collapsing white space as XML
+// does by default.
+type ttmlText struct {
+	spans []Span
+	// times are the begin and end of the spans, the end negative while open
+	times [][2]time.Duration
+	// space is a collapsed space waiting for more text on the line
+	space bool
+}
+
+func isXMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
+
+// ttmlAttr returns the value of the attribute with the local name.
+func ttmlAttr(attrs []xml.Attr, name string) (string, bool) {
+	for _, attr := range attrs {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func (t *ttmlText) atLineStart() bool {
+	return len(t.spans) == 0 ||
+		strings.HasSuffix(t.spans[len(t.spans)-1].Text, "\n")
+}
+
+// add appends a span presented at the times of the context.
+func (t *ttmlText) add(span Span, context ttmlContext) {
+	t.spans = append(t.spans, span)
+	t.times = append(t.times, [2]time.Duration{context.begin, context.end})
+}
+
+func (t *ttmlText) write(text string, context ttmlContext) {
+	style := context.style
+	if context.preserve {
+		style.Text = strings.ReplaceAll(text, "\r\n", "\n")
+		t.add(style, context)
+		t.space = false
+
+		return
+	}
+
+	words := strings.FieldsFunc(text, isXMLSpace)
+	if len(words) == 0 {
+		t.space = t.space || text != ""
+		return
+	}
+
+	// The space between words stays with the text before it
+	leading := strings.IndexFunc(text, isXMLSpace) == 0
+	if (leading || t.space) && !t.atLineStart() {
+		t.spans[len(t.spans)-1].Text += " "
+	}
+
+	style.Text = strings.Join(words, " ")
+	t.add(style, context)
+	t.space = strings.LastIndexFunc(text, isXMLSpace) == len(text)-1
+}
+
+func (t *ttmlText) lineBreak(context ttmlContext) {
+	style := context.style
+	style.Text = "\n"
+	t.add(style, context)
+	t.space = false
+}
+
+// cues returns the paragraph as cues, split at the times its spans begin
+// and end, each showing the spans presented throughout it.
+func (t *ttmlText) cues(paragraph Subtitle) []Subtitle {
+	bounds := []time.Duration{paragraph.Start, paragraph.End}
+
+	for _, times := range t.times {
+		for _, at := range times {
+			if at > paragraph.Start && at < paragraph.End {
+				bounds = append(bounds, at)
+			}
+		}
+	}
+
+	if len(bounds) == 2 {
+		paragraph.Text = spansText(t.spans)
+		paragraph.Spans = styledText(t.spans)
+
+		return []Subtitle{paragraph}
+	}
+
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	cues := make([]Subtitle, 0, len(bounds)-1)
+
+	for i := 1; i < len(bounds); i++ {
+		var spans []Span
+
+		for j, span := range t.spans {
+			begin, end := t.times[j][0], t.times[j][1]
+			if end < 0 {
+				end = paragraph.End
+			}
+
+			if begin <= bounds[i-1] && end >= bounds[i] {
+				spans = append(spans, span)
+			}
+		}
+
+		// Spaces and line breaks around the spans left out are dropped
+		if n := len(spans); n > 0 {
+			spans[0].Text = strings.TrimLeftFunc(spans[0].Text, unicode.IsSpace)
+			spans[n-1].Text = strings.TrimRightFunc(
+				spans[n-1].Text,
+				unicode.IsSpace,
+			)
+		}
+
+		cue := paragraph
+		cue.Start, cue.End = bounds[i-1], bounds[i]
+		cue.Text = spansText(spans)
+		cue.Spans = styledText(spans)
+
+		// Identifiers stay unique
+		if i > 1 {
+			cue.Vtt.ID = ""
+		}
+
+		cues = append(cues, cue)
+	}
+
+	return cues
+}
+
+// parseTtmlTiming reads the time parameters of the <tt> element.
+func parseTtmlTiming(attrs []xml.Attr) (ttmlTiming, error) {
+	timing := ttmlTiming{
+		frameRate: FrameRate{Num: ttmlFrameRate, Den: 1},
+		tickRate:  FrameRate{Num: 1, Den: 1},
+	}
+
+	value, hasRate := ttmlAttr(attrs, "frameRate")
+	if hasRate {
+		rate, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || rate <= 0 {
+			return timing, &valueError{
+				field: "ttp:frameRate",
+				value: value,
+				err:   ErrInvalidFrameRate,
+			}
+		}
+
+		timing.frameRate.Num = rate
+	}
+
+	if value, ok := ttmlAttr(attrs, "frameRateMultiplier"); ok {
+		var num, den int64
+
+		_, err := fmt.Sscanf(value, "%d %d", &num, &den)
+		if err != nil || num <= 0 || den <= 0 {
+			return timing, &valueError{
+				field: "ttp:frameRateMultiplier",
+				value: value,
+				err:   ErrInvalidFrameRate,
+			}
+		}
+
+		timing.frameRate.Num *= num
+		timing.frameRate.Den *= den
+	}
+
+	// Ticks are frames when only the frame rate is set
+	if hasRate {
+		timing.tickRate = timing.frameRate
+	}
+
+	if value, ok := ttmlAttr(attrs, "tickRate"); ok {
+		rate, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || rate <= 0 {
+			return timing, &valueError{
+				field: "ttp:tickRate",
+				value: value,
+				err:   ErrInvalidFrameRate,
+			}
+		}
+
+		timing.tickRate = FrameRate{Num: rate, Den: 1}
+	}
+
+	return timing, nil
+}
+
+// seconds converts a count of units at the rate to a duration rounded to
+// the millisecond.
+func (r FrameRate) seconds(count float64) time.Duration {
+	millis := math.Round(count * float64(r.Den) * 1000 / float64(r.Num))
+	return time.Duration(millis) * time.Millisecond
+}
+
+// parseTtmlTime parses a clock time, e.g. 00:00:01.5 or 00:00:01:12 with
+// frames, or an offset time, e.g. 1.5s, 1500ms, 36f or 3000t.
+func (t ttmlTiming) parseTtmlTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if match := ttmlClockPattern.FindStringSubmatch(value); match != nil {
+		var clock [3]int64
+		for i := range clock {
+			clock[i], _ = strconv.ParseInt(match[i+1], 10, 64)
+		}
+
+		if clock[1] > 59 || clock[2] > 59 {
+			return 0, strconv.ErrRange
+		}
+
+		d := time.Duration(clock[0])*time.Hour +
+			time.Duration(clock[1])*time.Minute +
+			time.Duration(clock[2])*time.Second
+
+		switch {
+		case match[4] != "":
+			fraction, _ := strconv.ParseFloat(match[4], 64)
+			d += time.Duration(math.Round(fraction*1000)) * time.Millisecond
+		case match[5] != "":
+			frames, _ := strconv.ParseInt(match[5], 10, 64)
+			if frames >= t.frameRate.timebase() {
+				return 0, ErrInvalidFrame
+			}
+
+			d += t.frameRate.seconds(float64(frames))
+		}
+
+		return d, nil
+	}
+
+	match := ttmlOffsetPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, errors.New("expected a clock or offset time")
+	}
+
+	count, _ := strconv.ParseFloat(match[1], 64)
+
+	switch match[2] {
+	case "h":
+		return FrameRate{Num: 1, Den: 3600}.seconds(count), nil
+	case "m":
+		return FrameRate{Num: 1, Den: 60}.seconds(count), nil
+	case "s":
+		return FrameRate{Num: 1, Den: 1}.seconds(count), nil
+	case "ms":
+		return FrameRate{Num: 1000, Den: 1}.seconds(count), nil
+	case "f":
+		return t.frameRate.seconds(count), nil
+	default:
+		return t.tickRate.seconds(count), nil
+	}
+}
+
+// parseTtmlColor returns a colour as #RRGGBB or a WebVTT colour name,
+// dropping the alpha of #RRGGBBAA and rgba() colours.
+func parseTtmlColor(value string) string {
+	value = strings.TrimSpace(value)
+
+	if hex, ok := strings.CutPrefix(value, "#"); ok && len(hex) == 8 {
+		value = "#" + hex[:6]
+	}
+
+	if args, ok := strings.CutPrefix(strings.ToLower(value), "rgb"); ok {
+		args = strings.TrimPrefix(args, "a")
+		args = strings.TrimSuffix(strings.TrimPrefix(args, "("), ")")
+
+		var rgb [3]uint64
+
+		parts := strings.Split(args, ",")
+		for i := range min(len(parts), len(rgb)) {
+			rgb[i], _ = strconv.ParseUint(strings.TrimSpace(parts[i]), 10, 8)
+		}
+
+		return fmt.Sprintf("#%02X%02X%02X", rgb[0], rgb[1], rgb[2])
+	}
+
+	if _, ok := namedColors[strings.ToLower(value)]; ok {
+		return strings.ToLower(value)
+	}
+
+	return normalizeColor(value)
+}
+
+// applyTtmlStyle applies the styling attributes, e.g. tts:fontStyle, to the
+// span.
+func applyTtmlStyle(span *Span, attrs []xml.Attr) {
+	for _, attr := range attrs {
+		value := strings.TrimSpace(attr.Value)
+
+		switch attr.Name.Local {
+		case "fontStyle":
+			span.Italic = value == "italic" || value == "oblique"
+		case "fontWeight":
+			span.Bold = value == "bold"
+		case "textDecoration":
+			for decoration := range strings.FieldsSeq(value) {
+				switch decoration {
+				case "underline":
+					span.Underline = true
+				case "noUnderline", "none":
+					span.Underline = false
+				}
+			}
+		case "color":
+			span.Color = parseTtmlColor(value)
+		case "fontFamily":
+			span.Font = strings.Trim(value, `"'`)
+		case "fontSize":
+			// Only pixel sizes, e.g. 24px or 24px 24px
+			size, _, _ := strings.Cut(value, " ")
+			if px, ok := strings.CutSuffix(size, "px"); ok {
+				if n, err := strconv.ParseFloat(px, 64); err == nil {
+					span.Size = int(math.Round(n))
+				}
+			}
+		}
+	}
+}
+
+// ttmlStyles resolves the styles referenced by ID, e.g. style="s1 s2",
+// which may reference further styles themselves.
+type ttmlStyles map[string]ttmlDefinition
+
+func (s ttmlStyles) apply(span *Span, refs string, seen map[string]bool) {
+	for id := range strings.FieldsSeq(refs) {
+		def, ok := s[id]
+		if !ok || seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		if nested, ok := ttmlAttr(def.Attrs, "style"); ok {
+			s.apply(span, nested, seen)
+		}
+
+		applyTtmlStyle(span, def.Attrs)
+	}
+}
+
+// parsePercentPair parses two percentages, e.g. "10% 80%".
+func parsePercentPair(value string) (x, y float64, ok bool) {
+	fields := strings.Fields(strings.ReplaceAll(value, ",", " "))
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+
+	var pair [2]float64
+
+	for i, field := range fields {
+		number, found := strings.CutSuffix(field, "%")
+		if !found {
+			return 0, 0, false
+		}
+
+		n, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+
+		pair[i] = n
+	}
+
+	return pair[0], pair[1], true
+}
+
+func formatPercent(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64) + "%"
+}
+
+// newTtmlRegion converts a TTML region to the WebVTT settings of a Region,
+// its height being rounded to whole lines. Only percentages are converted.
+func newTtmlRegion(def ttmlDefinition) Region {
+	var settings []string
+
+	if value, ok := ttmlAttr(def.Attrs, "extent"); ok {
+		if width, height, ok := parsePercentPair(value); ok {
+			lines := max(1, int(math.Round(height/ttmlLineHeight)))
+			settings = append(
+				settings,
+				"width:"+formatPercent(width),
+				"lines:"+strconv.Itoa(lines),
+			)
+		}
+	}
+
+	if value, ok := ttmlAttr(def.Attrs, "origin"); ok {
+		if x, y, ok := parsePercentPair(value); ok {
+			settings = append(
+				settings,
+				"regionanchor:0%,0%",
+				"viewportanchor:"+formatPercent(x)+","+formatPercent(y),
+			)
+		}
+	}
+
+	return Region{ID: def.ID, Settings: strings.Join(settings, " ")}
+}
+
+// ttmlRegionBox returns the position and size of a region with settings in
+// the WebVTT syntax, in percent of the video, using the WebVTT defaults.
+func ttmlRegionBox(region Region) (x, y, width, height float64) {
+	var (
+		lines          = 3.0
+		anchorX, anchY = 0.0, 100.0
+		viewX, viewY   = 0.0, 100.0
+	)
+
+	width = 100
+
+	for setting := range strings.FieldsSeq(region.Settings) {
+		name, value, _ := strings.Cut(setting, ":")
+
+		switch name {
+		case "width":
+			if n, err := strconv.ParseFloat(
+				strings.TrimSuffix(value, "%"),
+				64,
+			); err == nil {
+				width = n
+			}
+		case "lines":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				lines = n
+			}
+		case "regionanchor":
+			if x, y, ok := parsePercentPair(value); ok {
+				anchorX, anchY = x, y
+			}
+		case "viewportanchor":
+			if x, y, ok := parsePercentPair(value); ok {
+				viewX, viewY = x, y
+			}
+		}
+	}
+
+	height = lines * ttmlLineHeight
+
+	return viewX - anchorX*width/100, viewY - anchY*height/100, width, height
+}
+
+// ttmlRegionLayout returns the tts:origin and tts:extent of a region with
+// settings in the WebVTT syntax.
+func ttmlRegionLayout(region Region) (origin, extent string) {
+	x, y, width, height := ttmlRegionBox(region)
+
+	return formatPercent(x) + " " + formatPercent(y),
+		formatPercent(width) + " " + formatPercent(height)
+}
+
+// ttmlRegionSetting returns the region of WebVTT cue settings, e.g.
+// "region:fred align:start".
+func ttmlRegionSetting(settings string) string {
+	for setting := range strings.FieldsSeq(settings) {
+		if region, ok := strings.CutPrefix(setting, "region:"); ok {
+			return region
+		}
+	}
+
+	return ""
+}
+
+// newTtmlSubtitlesIter reads TTML and DFXP documents. Cues are the <p>
+// elements, timed by begin, end and dur attributes on them and their
+// ancestors, and split where timed <span> elements appear or disappear. They
+// are styled by the styles they reference and their tts:
+// attributes, which nested <span> elements override. Cues in a region have
+// the "region:ID" setting of WebVTT.
+func newTtmlSubtitlesIter(
+	reader io.Reader,
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		buffered := bufio.NewReader(reader)
+		if bom, err := buffered.Peek(len(byteOrderMark)); err == nil &&
+			bytes.Equal(bom, []byte(byteOrderMark)) {
+			buffered.Discard(len(bom))
+		}
+
+		var (
+			decoder = xml.NewDecoder(buffered)
+			errs    = &errorPolicy{lenient: config.Lenient}
+			doc     = config.document()
+			timing  ttmlTiming
+			styles  = make(ttmlStyles)
+			// stack holds the context of the open timed elements
+			stack = []ttmlContext{{end: -1}}
+			// cue is the paragraph being read, and its text
+			cue  *Subtitle
+			text ttmlText
+			root bool
+		)
+
+		parseError := func(err error) *ParseError {
+			line, column := decoder.InputPos()
+
+			return &ParseError{
+				Source: config.Name,
+				Line:   line,
+				Column: column,
+				Err:    err,
+			}
+		}
+
+		// enter returns the context of a timed element within its parent
+		enter := func(start xml.StartElement) (ttmlContext, error) {
+			parent := stack[len(stack)-1]
+			context := parent
+			context.end = -1
+
+			if value, ok := ttmlAttr(start.Attr, "begin"); ok {
+				begin, err := timing.parseTtmlTime(value)
+				if err != nil {
+					return context, &valueError{
+						field: "begin time",
+						value: value,
+						err:   err,
+					}
+				}
+
+				context.begin += begin
+			}
+
+			if value, ok := ttmlAttr(start.Attr, "end"); ok {
+				end, err := timing.parseTtmlTime(value)
+				if err != nil {
+					return context, &valueError{
+						field: "end time",
+						value: value,
+						err:   err,
+					}
+				}
+
+				context.end = parent.begin + end
+			}
+
+			if value, ok := ttmlAttr(start.Attr, "dur"); ok {
+				dur, err := timing.parseTtmlTime(value)
+				if err != nil {
+					return context, &valueError{
+						field: "duration",
+						value: value,
+						err:   err,
+					}
+				}
+
+				if context.end < 0 || context.begin+dur < context.end {
+					context.end = context.begin + dur
+				}
+			}
+
+			if parent.end >= 0 &&
+				(context.end < 0 || context.end > parent.end) {
+				context.end = parent.end
+			}
+
+			if refs, ok := ttmlAttr(start.Attr, "style"); ok {
+				styles.apply(&context.style, refs, make(map[string]bool))
+			}
+
+			applyTtmlStyle(&context.style, start.Attr)
+
+			if region, ok := ttmlAttr(start.Attr, "region"); ok {
+				context.region = region
+			}
+
+			if space, ok := ttmlAttr(start.Attr, "space"); ok {
+				context.preserve = space == "preserve"
+			}
+
+			return context, nil
+		}
+
+		for {
+			token, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				var syntaxErr *xml.SyntaxError
+				if errors.As(err, &syntaxErr) {
+					yield(Subtitle{}, &ParseError{
+						Source: config.Name,
+						Line:   syntaxErr.Line,
+						Column: 1,
+						Err:    errors.New(syntaxErr.Msg),
+					})
+
+					return
+				}
+
+				yield(
+					Subtitle{},
+					fmt.Errorf("error reading ttml subtitle: %w", err),
+				)
+
+				return
+			}
+
+			switch token := token.(type) {
+			case xml.StartElement:
+				name := token.Name.Local
+
+				switch {
+				case !root && name != "tt":
+					yield(Subtitle{}, parseError(fmt.Errorf(
+						"expected <tt> root element, got <%s>",
+						name,
+					)))
+
+					return
+				case name == "tt":
+					root = true
+
+					timing, err = parseTtmlTiming(token.Attr)
+					if err != nil {
+						yield(Subtitle{}, parseError(err))
+						return
+					}
+
+					if _, ok := ttmlAttr(token.Attr, "frameRate"); ok {
+						doc.FrameRate = timing.frameRate
+					}
+
+					if lang, ok := ttmlAttr(token.Attr, "lang"); ok {
+						doc.Language = lang
+					}
+
+					continue
+				case name == "head":
+					var head ttmlHead
+					if err := decoder.DecodeElement(&head, &token); err != nil {
+						yield(Subtitle{}, parseError(err))
+						return
+					}
+
+					doc.Title = strings.TrimSpace(head.Title)
+
+					for _, def := range head.Styles {
+						styles[def.ID] = def
+					}
+
+					for _, def := range head.Regions {
+						doc.Regions = append(doc.Regions, newTtmlRegion(def))
+					}
+
+					continue
+				case name == "br" && cue != nil:
+					text.lineBreak(stack[len(stack)-1])
+					decoder.Skip()
+
+					continue
+				case name != "body" && name != "div" && name != "p" &&
+					(name != "span" || cue == nil):
+					// e.g. <metadata> or <set>
+					decoder.Skip()
+					continue
+				}
+
+				context, err := enter(token)
+				if err != nil {
+					parseErr := parseError(err)
+					if !errs.skip(parseErr) {
+						yield(Subtitle{}, parseErr)
+						return
+					}
+
+					// Skip the element with its content
+					decoder.Skip()
+
+					continue
+				}
+
+				stack = append(stack, context)
+
+				if name == "p" {
+					cue = &Subtitle{Start: context.begin, End: context.end}
+					cue.Vtt.ID, _ = ttmlAttr(token.Attr, "id")
+					text = ttmlText{}
+				}
+			case xml.CharData:
+				if cue != nil {
+					text.write(string(token), stack[len(stack)-1])
+				}
+			case xml.EndElement:
+				name := token.Name.Local
+				if name != "body" && name != "div" && name != "p" &&
+					name != "span" {
+					continue
+				}
+
+				context := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+
+				if name != "p" || cue == nil {
+					continue
+				}
+
+				if cue.End < 0 {
+					cue.End = cue.Start + config.displayTime()
+				}
+
+				if context.region != "" {
+					cue.Vtt.Settings = "region:" + context.region
+				}
+
+				paragraph := *cue
+				cue = nil
+
+				for _, sub := range text.cues(paragraph) {
+					if strings.TrimSpace(sub.Text) == "" {
+						continue
+					}
+
+					if !yield(sub, nil) {
+						return
+					}
+				}
+			}
+		}
+
+		if !root {
+			yield(Subtitle{}, &ParseError{
+				Source: config.Name,
+				Line:   1,
+				Column: 1,
+				Err:    errors.New("missing <tt> root element"),
+			})
+
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+// ttmlAttribute is an attribute written by the TTML printer, e.g.
+// {"tts:color", "#FF0000"}.
+type ttmlAttribute struct {
+	name  string
+	value string
+}
+
+func formatTtmlAttrs(attrs []ttmlAttribute) string {
+	var b strings.Builder
+
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, ` %s="%s"`, attr.name, ttmlEscape(attr.value))
+	}
+
+	return b.String()
+}
+
+// isNCName reports whether the value can be an xml:id, which starts with a
+// letter or underscore and has no colons or spaces.
+func isNCName(value string) bool {
+	for i, r := range value {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' ||
+			unicode.Is(unicode.Mn, r)):
+		default:
+			return false
+		}
+	}
+
+	return value != ""
+}
+
+// ttmlClock formats a time as a clock time, e.g. 00:00:01.500.
+func ttmlClock(d time.Duration) string {
+	var b strings.Builder
+
+	writeClockDuration(&b, d, ".")
+
+	return b.String()
+}
+
+// ttmlSpanAttrs returns the tts: attributes giving the style of the span.
+func ttmlSpanAttrs(span Span) []ttmlAttribute {
+	var attrs []ttmlAttribute
+
+	if span.Italic {
+		attrs = append(attrs, ttmlAttribute{"tts:fontStyle", "italic"})
+	}
+
+	if span.Bold {
+		attrs = append(attrs, ttmlAttribute{"tts:fontWeight", "bold"})
+	}
+
+	if span.Underline {
+		attrs = append(attrs, ttmlAttribute{"tts:textDecoration", "underline"})
+	}
+
+	if hex, ok := colorHex(span.Color); ok {
+		attrs = append(attrs, ttmlAttribute{"tts:color", "#" + hex})
+	}
+
+	if span.Font != "" {
+		attrs = append(attrs, ttmlAttribute{"tts:fontFamily", span.Font})
+	}
+
+	if span.Size != 0 {
+		attrs = append(
+			attrs,
+			ttmlAttribute{"tts:fontSize", strconv.Itoa(span.Size) + "px"},
+		)
+	}
+
+	return attrs
+}
+
+// ttmlCueAttrs returns the attributes of the <p> of the cue. The ID is
+// only written when it is a valid xml:id.
+func ttmlCueAttrs(sub Subtitle, region string) []ttmlAttribute {
+	var attrs []ttmlAttribute
+
+	if isNCName(sub.Vtt.ID) {
+		attrs = append(attrs, ttmlAttribute{"xml:id", sub.Vtt.ID})
+	}
+
+	attrs = append(
+		attrs,
+		ttmlAttribute{"begin", ttmlClock(sub.Start)},
+		ttmlAttribute{"end", ttmlClock(sub.End)},
+	)
+
+	if region != "" {
+		attrs = append(attrs, ttmlAttribute{"region", region})
+	}
+
+	return attrs
+}
+
+// ttmlRegionAttrs returns the attributes of the <region> of the region.
+func ttmlRegionAttrs(region Region) []ttmlAttribute {
+	origin, extent := ttmlRegionLayout(region)
+
+	return []ttmlAttribute{
+		{"xml:id", region.ID},
+		{"tts:origin", origin},
+		{"tts:extent", extent},
+	}
+}
+
+// formatTtmlText renders the spans as TTML content, with <br/> line breaks
+// and a <span> for each styled span.
+func formatTtmlText(spans []Span) string {
+	var b strings.Builder
+
+	for i, line := range spanLines(flattenRuby(spans)) {
+		if i > 0 {
+			b.WriteString("<br/>")
+		}
+
+		for _, span := range line {
+			attrs := ttmlSpanAttrs(span)
+			if attrs == nil {
+				b.WriteString(ttmlEscape(span.Text))
+				continue
+			}
+
+			fmt.Fprintf(
+				&b,
+				"<span%s>%s</span>",
+				formatTtmlAttrs(attrs),
+				ttmlEscape(span.Text),
+			)
+		}
+	}
+
+	return b.String()
+}
+
+// writeTtmlHeader writes the document up to the <div> of the cues in the
+// language, with the extra attributes of the <tt> element, e.g. a profile.
+func writeTtmlHeader(
+	w io.Writer,
+	doc *Document,
+	lang string,
+	root []ttmlAttribute,
+	regions []Region,
+) error {
+	attrs := append([]ttmlAttribute{
+		{"xmlns", ttmlNamespace},
+		{"xmlns:tts", ttmlStylingNamespace},
+		{"xmlns:ttp", ttmlParameterNamespace},
+		{"xmlns:ttm", ttmlMetadataNamespace},
+	}, root...)
+	attrs = append(
+		attrs,
+		ttmlAttribute{"ttp:timeBase", "media"},
+		ttmlAttribute{"xml:lang", lang},
+	)
+
+	_, err := fmt.Fprintf(
+		w,
+		"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<tt%s>\n  <head>\n",
+		formatTtmlAttrs(attrs),
+	)
+	if err != nil {
+		return err
+	}
+
+	if doc.Title != "" {
+		_, err := fmt.Fprintf(
+			w,
+			"    <metadata>\n"+
+				"      <ttm:title>%s</ttm:title>\n"+
+				"    </metadata>\n",
+			ttmlEscape(doc.Title),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(regions) > 0 {
+		if _, err := fmt.Fprintln(w, "    <layout>"); err != nil {
+			return err
+		}
+
+		for _, region := range regions {
+			_, err := fmt.Fprintf(
+				w,
+				"      <region%s/>\n",
+				formatTtmlAttrs(ttmlRegionAttrs(region)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintln(w, "    </layout>"); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprint(w, "  </head>\n  <body>\n    <div>\n")
+
+	return err
+}
+
+func writeTtmlCue(w io.Writer, attrs []ttmlAttribute, sub Subtitle) error {
+	_, err := fmt.Fprintf(
+		w,
+		"      <p%s>%s</p>\n",
+		formatTtmlAttrs(attrs),
+		formatTtmlText(sub.spans()),
+	)
+
+	return err
+}
+
+func writeTtmlSubtitle(w io.Writer, sub Subtitle) error {
+	attrs := ttmlCueAttrs(sub, ttmlRegionSetting(sub.Vtt.Settings))
+	return writeTtmlCue(w, attrs, sub)
+}
+
+func writeTtmlFooter(w io.Writer) error {
+	_, err := fmt.Fprint(w, "    </div>\n  </body>\n</tt>\n")
+	return err
+}
+
+func newTtmlPrinter(writer io.Writer, config PrinterConfig) Printer {
+	return &printer{
+		writer: writer,
+		header: func(w io.Writer, _ *Subtitle) error {
+			doc := config.document()
+
+			return writeTtmlHeader(
+				w,
+				doc,
+				config.language(),
+				nil,
+				doc.Regions,
+			)
+		},
+		write:  writeTtmlSubtitle,
+		footer: writeTtmlFooter,
+	}
+}
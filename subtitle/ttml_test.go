@@ -0,0 +1,306 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ttmlInput = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling"
+    xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:ttm="http://www.w3.org/ns/ttml#metadata"
+    ttp:frameRate="25" ttp:tickRate="10000000" xml:lang="en">
+  <head>
+    <metadata><ttm:title>Movie</ttm:title></metadata>
+    <styling>
+      <style xml:id="base" tts:fontFamily="Arial" tts:color="#FFFF00FF"/>
+      <style xml:id="emphasis" style="base" tts:fontStyle="italic"/>
+    </styling>
+    <layout>
+      <region xml:id="bottom" tts:origin="10% 80%" tts:extent="80% 10.66%"/>
+    </layout>
+  </head>
+  <body region="bottom">
+    <div begin="10s">
+      <p xml:id="p1" begin="00:00:01.5" end="00:00:03:12">
+        Hello
+        <span style="emphasis">nested <span tts:fontWeight="bold">world</span></span>
+      </p>
+      <p begin="50000000t" dur="25f">Line one<br/>line two<metadata>ignored</metadata></p>
+    </div>
+    <p begin="1h" end="3600.5s" xml:space="preserve">  two  spaces</p>
+  </body>
+</tt>
+`
+
+func TestTtmlTiming_ParseTime(t *testing.T) {
+	timing := ttmlTiming{
+		frameRate: FrameRate{Num: 30000, Den: 1001},
+		tickRate:  FrameRate{Num: 10000000, Den: 1},
+	}
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"00:00:01", time.Second},
+		{"01:02:03.25", time.Hour + 2*time.Minute + 3250*time.Millisecond},
+		{"00:00:01:15", 1500*time.Millisecond + 500*time.Microsecond},
+		{"00:00:01:15.1", 1500*time.Millisecond + 500*time.Microsecond},
+		{"1.5h", 90 * time.Minute},
+		{"2m", 2 * time.Minute},
+		{"2.5s", 2500 * time.Millisecond},
+		{"250ms", 250 * time.Millisecond},
+		{"30f", 1001 * time.Millisecond},
+		{"25000000t", 2500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := timing.parseTtmlTime(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want.Round(time.Millisecond) {
+				t.Errorf("expected %v, got %v", tt.want.Round(time.Millisecond), got)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "1", "1x", "00:61:00", "00:00:00:30", "-1s"} {
+		if _, err := timing.parseTtmlTime(value); err == nil {
+			t.Errorf("%q: expected error", value)
+		}
+	}
+}
+
+func TestNewSubtitlesIter_TtmlFormat(t *testing.T) {
+	var (
+		doc  Document
+		subs []Subtitle
+	)
+
+	config := ReaderConfig{Document: &doc}
+	for sub, err := range NewSubtitlesIterWithConfig(strings.NewReader(ttmlInput), TtmlFormat, config) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	want := []Subtitle{
+		{
+			Start: 11500 * time.Millisecond,
+			End:   13480 * time.Millisecond,
+			Text:  "Hello nested world",
+			Spans: &StyledText{
+				{Text: "Hello "},
+				{Text: "nested ", Italic: true, Font: "Arial", Color: "#FFFF00"},
+				{Text: "world", Italic: true, Bold: true, Font: "Arial", Color: "#FFFF00"},
+			},
+			Vtt: VttCue{ID: "p1", Settings: "region:bottom"},
+		},
+		{
+			Start: 15 * time.Second,
+			End:   16 * time.Second,
+			Text:  "Line one\nline two",
+			Vtt:   VttCue{Settings: "region:bottom"},
+		},
+		{
+			Start: time.Hour,
+			End:   time.Hour + 500*time.Millisecond,
+			Text:  "  two  spaces",
+			Vtt:   VttCue{Settings: "region:bottom"},
+		},
+	}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("expected %+v, got %+v", want, subs)
+	}
+
+	wantRegions := []Region{{ID: "bottom", Settings: "width:80% lines:2 regionanchor:0%,0% viewportanchor:10%,80%"}}
+	if doc.Title != "Movie" || doc.Language != "en" || doc.FrameRate != (FrameRate{25, 1}) ||
+		!reflect.DeepEqual(doc.Regions, wantRegions) {
+		t.Errorf("unexpected document %+v", doc)
+	}
+}
+
+func TestNewSubtitlesIter_TtmlFormat_Dfxp(t *testing.T) {
+	input := "\uFEFF<tt xmlns=\"http://www.w3.org/2006/10/ttaf1\" ttp:frameRate=\"24\" ttp:frameRateMultiplier=\"1000 1001\"" +
+		" xmlns:ttp=\"http://www.w3.org/2006/10/ttaf1#parameter\"><body><div>" +
+		"<p begin=\"24f\" end=\"48f\">Film &amp; video</p>" +
+		"<p begin=\"3s\">Open</p>" +
+		"</div></body></tt>"
+
+	var subs []Subtitle
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), TtmlFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	want := []Subtitle{
+		{Start: 1001 * time.Millisecond, End: 2002 * time.Millisecond, Text: "Film & video"},
+		{Start: 3 * time.Second, End: 6 * time.Second, Text: "Open"},
+	}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("expected %+v, got %+v", want, subs)
+	}
+}
+
+func TestNewSubtitlesIter_TtmlFormat_TimedSpans(t *testing.T) {
+	input := "<tt xmlns=\"http://www.w3.org/ns/ttml\"><body><div>" +
+		"<p xml:id=\"a\" begin=\"1s\" end=\"5s\">Hello <span begin=\"1s\">world</span></p>" +
+		"<p begin=\"10s\" end=\"13s\"><span end=\"1s\">One</span><br/><span begin=\"1s\" dur=\"1s\">Two</span></p>" +
+		"</div></body></tt>"
+
+	var subs []Subtitle
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(input), TtmlFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	want := []Subtitle{
+		{Vtt: VttCue{ID: "a"}, Start: time.Second, End: 2 * time.Second, Text: "Hello"},
+		{Start: 2 * time.Second, End: 5 * time.Second, Text: "Hello world"},
+		{Start: 10 * time.Second, End: 11 * time.Second, Text: "One"},
+		{Start: 11 * time.Second, End: 12 * time.Second, Text: "Two"},
+	}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("expected %+v, got %+v", want, subs)
+	}
+}
+
+func TestNewSubtitlesIter_TtmlFormat_Errors(t *testing.T) {
+	input := "<tt>\n<body>\n" +
+		"<p begin=\"1s\" end=\"2s\">First</p>\n" +
+		"<p begin=\"x\" end=\"4s\">Broken <span>text</span></p>\n" +
+		"<p begin=\"5s\" end=\"6s\">Last</p>\n" +
+		"</body>\n</tt>\n"
+
+	var parseErr *ParseError
+
+	for _, err := range NewSubtitlesIter(strings.NewReader(input), TtmlFormat) {
+		if err != nil && !errors.As(err, &parseErr) {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+	}
+
+	if parseErr == nil || parseErr.Line != 4 || !strings.Contains(parseErr.Error(), `invalid begin time "x"`) {
+		t.Fatalf("expected an invalid begin time on line 4, got %v", parseErr)
+	}
+
+	var (
+		texts   []string
+		skipped *SkippedCuesError
+	)
+
+	subs := NewSubtitlesIterWithConfig(strings.NewReader(input), TtmlFormat, ReaderConfig{Lenient: true})
+	for sub, err := range subs {
+		if errors.As(err, &skipped) {
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if !reflect.DeepEqual(texts, []string{"First", "Last"}) || skipped == nil || len(skipped.Errors) != 1 {
+		t.Errorf("expected 2 cues and 1 skipped, got %q, %v", texts, skipped)
+	}
+
+	for _, input := range []string{"<html></html>", "<tt><body><p>unclosed</body></tt>", ""} {
+		var got error
+
+		for _, err := range NewSubtitlesIter(strings.NewReader(input), TtmlFormat) {
+			got = err
+		}
+
+		if !errors.As(got, &parseErr) {
+			t.Errorf("%q: expected ParseError, got %v", input, got)
+		}
+	}
+}
+
+func TestNewSubtitlePrinter_TtmlFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	doc := &Document{
+		Language: "en",
+		Title:    "A & B",
+		Regions:  []Region{{ID: "fred", Settings: "width:40% lines:3 regionanchor:0%,100% viewportanchor:10%,90%"}},
+	}
+	printer := NewSubtitlePrinterWithConfig(&buf, TtmlFormat, PrinterConfig{Document: doc})
+
+	subs := []Subtitle{
+		{
+			Start: time.Second,
+			End:   2500 * time.Millisecond,
+			Text:  "Hi <you>\nthere",
+			Spans: &StyledText{{Text: "Hi "}, {Text: "<you>", Italic: true, Color: "#FF0000"}, {Text: "\nthere"}},
+			Vtt:   VttCue{Settings: "region:fred align:start"},
+		},
+		{Vtt: VttCue{ID: "c2"}, Start: time.Hour, End: time.Hour + time.Second, Text: "Plain"},
+	}
+	for _, sub := range subs {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" ttp:timeBase="media" xml:lang="en">
+  <head>
+    <metadata>
+      <ttm:title>A &amp; B</ttm:title>
+    </metadata>
+    <layout>
+      <region xml:id="fred" tts:origin="10% 74.01%" tts:extent="40% 15.99%"/>
+    </layout>
+  </head>
+  <body>
+    <div>
+      <p begin="00:00:01.000" end="00:00:02.500" region="fred">Hi <span tts:fontStyle="italic" tts:color="#FF0000">&lt;you&gt;</span><br/>there</p>
+      <p xml:id="c2" begin="01:00:00.000" end="01:00:01.000">Plain</p>
+    </div>
+  </body>
+</tt>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// The output reads back to the same cues
+	var got []Subtitle
+
+	for sub, err := range NewSubtitlesIter(strings.NewReader(want), TtmlFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got = append(got, sub)
+	}
+
+	subs[0].Vtt.Settings = "region:fred"
+	if !reflect.DeepEqual(got, subs) {
+		t.Errorf("expected %+v after a round trip, got %+v", subs, got)
+	}
+}
@@ -0,0 +1,359 @@
+package subtitle
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"time"
+)
+
+const (
+	vttSignature = "WEBVTT"
+	vttStyle     = "STYLE"
+	vttRegion    = "REGION"
+)
+
+// VttCue holds the properties of a WebVTT cue. TTML keeps the xml:id and the
+// region of its paragraphs in them as well.
+type VttCue struct {
+	// ID is an optional cue identifier
+	ID string
+	// Settings are optional cue settings, e.g. "align:start line:0"
+	Settings string
+}
+
+// isVttKeyword reports whether line starts with keyword followed by a space,
+// a tab or the end of the line.
+func isVttKeyword(line, keyword string) bool {
+	rest, found := strings.CutPrefix(line, keyword)
+
+	return found &&
+		(rest == "" || strings.HasPrefix(rest, " ") ||
+			strings.HasPrefix(rest, "\t"))
+}
+
+// parseVttHeader reads the lines following the signature. A "Language: en"
+// line sets the language, and the others are kept verbatim, since header
+// lines are not all "Key: Value" pairs, e.g.
+// "X-TIMESTAMP-MAP=LOCAL:00:00:00.000,MPEGTS:900000".
+func parseVttHeader(doc *Document, lines []string) {
+	for _, line := range lines {
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(key) != "Language" {
+			doc.Metadata = append(doc.Metadata, MetadataField{Value: line})
+			continue
+		}
+
+		doc.Language = strings.TrimSpace(value)
+		doc.Metadata = append(
+			doc.Metadata,
+			MetadataField{Key: "Language", Value: doc.Language},
+		)
+	}
+}
+
+// newVttRegion parses the settings of a REGION block, e.g. "id:fred" and
+// "width:40%", which may share lines.
+func newVttRegion(lines []string) Region {
+	var (
+		region   Region
+		settings []string
+	)
+
+	for _, setting := range strings.Fields(strings.Join(lines, " ")) {
+		if id, found := strings.CutPrefix(setting, "id:"); found {
+			region.ID = id
+			continue
+		}
+
+		settings = append(settings, setting)
+	}
+
+	region.Settings = strings.Join(settings, " ")
+
+	return region
+}
+
+func parseVttDuration(value string) (time.Duration, error) {
+	// Parse format: [HH:]MM:SS.mmm
+	if strings.Count(value, ":") == 1 {
+		value = "00:" + value
+	}
+
+	if !strings.Contains(value, ".") {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	return parseSrtDuration(value)
+}
+
+func newSubtitleFromVtt(
+	line string,
+	readLine func() (string, bool),
+) (sub Subtitle, err error) {
+	// Parse block:
+	// identifier
+	// 00:05.120 --> 00:06.840 align:start
+	// text
+	// text
+	//
+	// The identifier is optional.
+	if !strings.Contains(line, "-->") {
+		sub.Vtt.ID = line
+
+		var ok bool
+		if line, ok = readLine(); !ok {
+			return sub, errors.New("missing timing line after identifier")
+		}
+	}
+
+	sub.Start, sub.End, sub.Vtt.Settings, err = parseCueTiming(
+		line,
+		parseVttDuration,
+	)
+	if err != nil {
+		return sub, err
+	}
+
+	var text []string
+
+	for {
+		line, ok := readLine()
+		if !ok || strings.TrimSpace(line) == "" {
+			break
+		}
+
+		text = append(text, line)
+	}
+
+	spans := parseTaggedText(strings.Join(text, "\n"), vttMarkup)
+	sub.Text = spansText(spans)
+	sub.Spans = styledText(spans)
+
+	return sub, nil
+}
+
+func newVttSubtitlesIter(
+	next func() (string, error, bool),
+	stop func(),
+	config ReaderConfig,
+) iter.Seq2[Subtitle, error] {
+	return func(yield func(Subtitle, error) bool) {
+		defer stop()
+
+		reader := &lineReader{next: next, name: config.Name}
+		errs := &errorPolicy{lenient: config.Lenient}
+		doc := config.document()
+
+		// The header block starts with the signature and runs until the
+		// first blank line. In lenient mode a missing signature is skipped
+		// and the first line parsed as part of a cue.
+		var first string
+
+		header, ok := reader.readLine()
+
+		switch {
+		case !ok:
+		case !isVttKeyword(header, vttSignature):
+			parseErr := reader.parseError(
+				fmt.Errorf("missing %s signature", vttSignature),
+			)
+			if !errs.skip(parseErr) {
+				yield(Subtitle{}, parseErr)
+				return
+			}
+
+			first = header
+		default:
+			// The signature may be followed by a title
+			doc.Title = strings.TrimSpace(header[len(vttSignature):])
+			parseVttHeader(doc, readBlock(reader.readLine))
+		}
+
+		for reader.err == nil {
+			line, ok := first, strings.TrimSpace(first) != ""
+			if first = ""; !ok {
+				line, ok = reader.skipBlank()
+			}
+
+			if !ok {
+				break
+			}
+
+			switch {
+			case isVttKeyword(line, "NOTE"):
+				skipBlock(reader.readLine)
+				continue
+			case isVttKeyword(line, vttStyle):
+				doc.Sections = append(doc.Sections, Section{
+					Name:  vttStyle,
+					Lines: readBlock(reader.readLine),
+				})
+
+				continue
+			case isVttKeyword(line, vttRegion):
+				doc.Regions = append(
+					doc.Regions,
+					newVttRegion(readBlock(reader.readLine)),
+				)
+
+				continue
+			}
+
+			sub, err := newSubtitleFromVtt(line, reader.readLine)
+			if reader.err != nil {
+				break
+			}
+
+			if err != nil {
+				if parseErr := reader.parseError(err); !errs.skip(parseErr) {
+					yield(Subtitle{}, parseErr)
+					return
+				}
+
+				// Resume at the next block
+				skipBlock(reader.readLine)
+
+				continue
+			}
+
+			if !yield(sub, nil) {
+				return
+			}
+		}
+
+		if reader.err != nil {
+			yield(
+				Subtitle{},
+				fmt.Errorf("error reading vtt subtitle: %w", reader.err),
+			)
+
+			return
+		}
+
+		if err := errs.report(); err != nil {
+			yield(Subtitle{}, err)
+		}
+	}
+}
+
+func writeVttDuration(w io.Writer, d time.Duration) error {
+	return writeClockDuration(w, d, ".")
+}
+
+func writeVttSubtitle(w io.Writer, sub Subtitle) error {
+	var err error
+
+	if sub.Vtt.ID != "" {
+		if _, err = fmt.Fprintln(w, sub.Vtt.ID); err != nil {
+			return err
+		}
+	}
+
+	if err = writeVttDuration(w, sub.Start); err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprint(w, " --> "); err != nil {
+		return err
+	}
+
+	if err = writeVttDuration(w, sub.End); err != nil {
+		return err
+	}
+
+	if sub.Vtt.Settings != "" {
+		if _, err = fmt.Fprint(w, " ", sub.Vtt.Settings); err != nil {
+			return err
+		}
+	}
+
+	text := formatTaggedText(sub.spans(), vttMarkup)
+	_, err = fmt.Fprintf(w, "\n%s\n\n", text)
+
+	return err
+}
+
+// writeVttHeader writes the signature with the title, the header lines in
+// the language and the regions and styles of the document.
+func writeVttHeader(w io.Writer, doc *Document, lang string) error {
+	// The title follows the signature on its line, where cue timings are
+	// not allowed
+	signature := vttSignature
+	title := strings.Join(strings.Fields(doc.Title), " ")
+
+	if title != "" && !strings.Contains(title, "-->") {
+		signature += " " + title
+	}
+
+	if _, err := fmt.Fprintln(w, signature); err != nil {
+		return err
+	}
+
+	metadata, sections := doc.metadata(VttFormat)
+	if len(metadata) == 0 && lang != "" {
+		metadata = []MetadataField{{Key: "Language"}}
+	}
+
+	for _, field := range metadata {
+		var err error
+
+		switch field.Key {
+		case "":
+			_, err = fmt.Fprintln(w, field.Value)
+		case "Language":
+			value := cmp.Or(lang, field.Value)
+			_, err = fmt.Fprintf(w, "%s: %s\n", field.Key, value)
+		default:
+			_, err = fmt.Fprintf(w, "%s: %s\n", field.Key, field.Value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	for _, region := range doc.Regions {
+		settings := append(
+			[]string{vttRegion, "id:" + region.ID},
+			strings.Fields(region.Settings)...,
+		)
+
+		_, err := fmt.Fprintf(w, "%s\n\n", strings.Join(settings, "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, section := range sections {
+		if section.Name != vttStyle {
+			continue
+		}
+
+		lines := append([]string{vttStyle}, section.Lines...)
+
+		_, err := fmt.Fprintf(w, "%s\n\n", strings.Join(lines, "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newVttPrinter(writer io.Writer, config PrinterConfig) Printer {
+	return &printer{
+		writer: writer,
+		header: func(w io.Writer, _ *Subtitle) error {
+			return writeVttHeader(w, config.document(), config.language())
+		},
+		write: writeVttSubtitle,
+	}
+}
@@ -0,0 +1,269 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSubtitlesIter_VttFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Subtitle
+	}{
+		{
+			name:  "single cue",
+			input: "WEBVTT\n\n00:00:05.120 --> 00:00:06.840\nHello",
+			want: []Subtitle{
+				{
+					Start: 5*time.Second + 120*time.Millisecond,
+					End:   6*time.Second + 840*time.Millisecond,
+					Text:  "Hello",
+				},
+			},
+		},
+		{
+			name: "header text, notes, style and identifiers",
+			input: `WEBVTT - Some title
+Kind: captions
+
+NOTE This is a comment
+spanning two lines
+
+STYLE
+::cue { color: red }
+
+intro
+00:01.000 --> 00:02.500 align:start line:0
+First line
+Second line
+
+NOTE
+
+01:02:03.004 --> 01:02:04.005
+Second cue
+`,
+			want: []Subtitle{
+				{
+					Start: 1 * time.Second,
+					End:   2*time.Second + 500*time.Millisecond,
+					Text:  "First line\nSecond line",
+					Vtt:   VttCue{ID: "intro", Settings: "align:start line:0"},
+				},
+				{
+					Start: 1*time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond,
+					End:   1*time.Hour + 2*time.Minute + 4*time.Second + 5*time.Millisecond,
+					Text:  "Second cue",
+				},
+			},
+		},
+		{
+			name:  "byte order mark and CRLF",
+			input: "\uFEFFWEBVTT\r\n\r\n1\r\n00:00:01.000 --> 00:00:02.000\r\nText\r\n",
+			want: []Subtitle{
+				{Start: 1 * time.Second, End: 2 * time.Second, Text: "Text", Vtt: VttCue{ID: "1"}},
+			},
+		},
+		{
+			name:  "header only",
+			input: "WEBVTT\n",
+			want:  []Subtitle{},
+		},
+		{
+			name:  "empty input",
+			input: "",
+			want:  []Subtitle{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter := NewSubtitlesIter(strings.NewReader(tt.input), VttFormat)
+
+			count := 0
+			for sub, err := range iter {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if count >= len(tt.want) {
+					t.Fatalf("got more subtitles than expected")
+				}
+
+				if sub != tt.want[count] {
+					t.Errorf("subtitle %d: expected %+v, got %+v", count, tt.want[count], sub)
+				}
+
+				count++
+			}
+
+			if count != len(tt.want) {
+				t.Errorf("expected %d subtitles, got %d", len(tt.want), count)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIter_VttFormat_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "missing signature",
+			input:   "00:00:01.000 --> 00:00:02.000\nText",
+			wantErr: "1:1: missing WEBVTT signature",
+		},
+		{
+			name:    "signature prefix only",
+			input:   "WEBVTTX\n\n00:00:01.000 --> 00:00:02.000\nText",
+			wantErr: "missing WEBVTT signature",
+		},
+		{
+			name:    "comma separator",
+			input:   "WEBVTT\n\n00:00:01,000 --> 00:00:02,000\nText",
+			wantErr: `3:1: invalid start time "00:00:01,000"`,
+		},
+		{
+			name:    "missing timing line",
+			input:   "WEBVTT\n\nidentifier",
+			wantErr: "missing timing line after identifier",
+		},
+		{
+			name:    "invalid end time",
+			input:   "WEBVTT\n\n00:01.000 --> 00:02\nText",
+			wantErr: `3:15: invalid end time "00:02"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter := NewSubtitlesIter(strings.NewReader(tt.input), VttFormat)
+
+			count := 0
+			for _, err := range iter {
+				count++
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected %q error, got: %v", tt.wantErr, err)
+				}
+			}
+
+			if count != 1 {
+				t.Errorf("expected iterator to yield error once, got %d", count)
+			}
+		})
+	}
+}
+
+func TestNewSubtitlesIterWithConfig_VttFormat_Lenient(t *testing.T) {
+	input := "00:00:01.000 --> 00:00:02.000\nFirst\n\n" +
+		"00:00:03,000 --> 00:00:04,000\nBroken\nStill broken\n\n" +
+		"NOTE comment\n\n" +
+		"00:05.000 --> 00:06.000\nSecond\n"
+
+	iter := NewSubtitlesIterWithConfig(
+		strings.NewReader(input),
+		VttFormat,
+		ReaderConfig{Lenient: true},
+	)
+
+	var (
+		texts   []string
+		skipped *SkippedCuesError
+	)
+
+	for sub, err := range iter {
+		if err != nil {
+			if !errors.As(err, &skipped) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			continue
+		}
+
+		texts = append(texts, sub.Text)
+	}
+
+	if len(texts) != 2 || texts[0] != "First" || texts[1] != "Second" {
+		t.Errorf("expected the valid cues, got %q", texts)
+	}
+
+	if skipped == nil || len(skipped.Errors) != 2 {
+		t.Fatalf("expected 2 skipped cues, got %v", skipped)
+	}
+
+	expected := []string{
+		"1:1: missing WEBVTT signature",
+		`4:1: invalid start time "00:00:03,000"`,
+	}
+	for i, parseErr := range skipped.Errors {
+		if parseErr.Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], parseErr.Error())
+		}
+	}
+}
+
+func TestNewSubtitlePrinter_VttFormat(t *testing.T) {
+	var buf bytes.Buffer
+	printer := NewSubtitlePrinter(&buf, VttFormat)
+
+	if printer == nil {
+		t.Fatal("expected printer function, got nil")
+	}
+
+	subtitles := []Subtitle{
+		{
+			Start: 1 * time.Second,
+			End:   2*time.Second + 500*time.Millisecond,
+			Text:  "First line\nSecond line",
+			Vtt:   VttCue{ID: "intro"},
+		},
+		{
+			Start: 1*time.Hour + 3*time.Second,
+			End:   1*time.Hour + 4*time.Second,
+			Text:  "Positioned",
+			Vtt:   VttCue{Settings: "align:start line:0"},
+		},
+	}
+
+	for _, sub := range subtitles {
+		if err := printer.Write(sub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := "WEBVTT\n\n" +
+		"intro\n00:00:01.000 --> 00:00:02.500\nFirst line\nSecond line\n\n" +
+		"01:00:03.000 --> 01:00:04.000 align:start line:0\nPositioned\n\n"
+
+	got := buf.String()
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+
+	// The output must parse back into the same cues
+	count := 0
+	for sub, err := range NewSubtitlesIter(strings.NewReader(got), VttFormat) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if sub != subtitles[count] {
+			t.Errorf("subtitle %d: expected %+v, got %+v", count, subtitles[count], sub)
+		}
+
+		count++
+	}
+
+	if count != len(subtitles) {
+		t.Errorf("expected %d subtitles, got %d", len(subtitles), count)
+	}
+}